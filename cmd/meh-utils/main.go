@@ -5,10 +5,20 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/gruppe-adler/meh-utils/internal/aspect"
+	"github.com/gruppe-adler/meh-utils/internal/cog"
+	"github.com/gruppe-adler/meh-utils/internal/colorrelief"
+	"github.com/gruppe-adler/meh-utils/internal/elevation"
+	"github.com/gruppe-adler/meh-utils/internal/hillshade"
+	"github.com/gruppe-adler/meh-utils/internal/los"
 	"github.com/gruppe-adler/meh-utils/internal/mvt"
 	"github.com/gruppe-adler/meh-utils/internal/preview"
+	"github.com/gruppe-adler/meh-utils/internal/profile"
+	"github.com/gruppe-adler/meh-utils/internal/quantizedmesh"
 	"github.com/gruppe-adler/meh-utils/internal/sat"
+	"github.com/gruppe-adler/meh-utils/internal/slope"
 	"github.com/gruppe-adler/meh-utils/internal/terrainrgb"
+	"github.com/gruppe-adler/meh-utils/internal/viewshed"
 )
 
 type command struct {
@@ -23,7 +33,17 @@ func init() {
 	subCommands = []command{
 		{"sat", "Build satellite tiles from grad_meh data.", sat.Run},
 		{"terrainrgb", "Build Terrain-RGB tiles from grad_meh data.", terrainrgb.Run},
+		{"quantizedmesh", "Build Cesium quantized-mesh terrain tiles from grad_meh data.", quantizedmesh.Run},
+		{"hillshade", "Build hillshade tiles from grad_meh data.", hillshade.Run},
+		{"colorrelief", "Build hypsometric color relief tiles from grad_meh data.", colorrelief.Run},
+		{"slope", "Build slope tiles from grad_meh data.", slope.Run},
+		{"aspect", "Build aspect tiles from grad_meh data.", aspect.Run},
 		{"mvt", "Build mapbox vector tiles from grad_meh data.", mvt.Run},
+		{"cog", "Export DEM and satellite image as Cloud-Optimized GeoTIFFs from grad_meh data.", cog.Run},
+		{"elevation", "Print elevations at world coordinates from grad_meh data.", elevation.Run},
+		{"viewshed", "Build viewshed tiles for observer positions from grad_meh data.", viewshed.Run},
+		{"los", "Check the line of sight between two positions from grad_meh data.", los.Run},
+		{"profile", "Print the elevation profile along a path from grad_meh data.", profile.Run},
 		{"preview", "Build resolutions for preview image.", preview.Run},
 		{"help", "Print this message.", func(s *flag.FlagSet) { printUsage() }},
 	}
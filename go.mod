@@ -4,6 +4,7 @@ go 1.16
 
 require (
 	github.com/gogo/protobuf v1.3.2 // indirect
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
 	github.com/paulmach/orb v0.2.1
 	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
@@ -0,0 +1,27 @@
+package aspect
+
+import (
+	"image"
+
+	"github.com/gruppe-adler/meh-utils/internal/colorramp"
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+)
+
+func calculateImage(raster dem.EsriASCIIRaster, ramp colorramp.Ramp) *image.RGBA {
+	w, h := raster.Dims()
+
+	img := image.NewRGBA(image.Rectangle{image.Point{0, 0}, image.Point{int(w), int(h)}})
+
+	for col := uint(0); col < w; col++ {
+		for row := uint(0); row < h; row++ {
+			// cells without data stay transparent
+			if raster.IsNoData(col, row) {
+				continue
+			}
+
+			img.Set(int(col), int(row), ramp.Color(raster.Aspect(col, row)))
+		}
+	}
+
+	return img
+}
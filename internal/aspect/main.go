@@ -0,0 +1,17 @@
+package aspect
+
+import (
+	"flag"
+
+	"github.com/gruppe-adler/meh-utils/internal/colorramp"
+	"github.com/gruppe-adler/meh-utils/internal/demtiles"
+)
+
+// Run is the program's entrypoint
+func Run(flagSet *flag.FlagSet) {
+	flags := demtiles.AddFlags(flagSet, "aspects in degrees clockwise from north, -1 for flat cells")
+
+	flags.Parse(flagSet)
+
+	demtiles.Build(flags, "aspect", colorramp.Aspect, calculateImage)
+}
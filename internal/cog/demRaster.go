@@ -0,0 +1,41 @@
+package cog
+
+import (
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+	"github.com/gruppe-adler/meh-utils/internal/geotiff"
+)
+
+// demRaster converts the DEM to a float32 raster with the elevation offset applied to all cells with data
+func demRaster(raster dem.EsriASCIIRaster, elevationOffset float64) geotiff.Float32Raster {
+	w, h := raster.Dims()
+
+	out := geotiff.Float32Raster{
+		Width:     int(w),
+		Height:    int(h),
+		Data:      make([]float32, w*h),
+		NoData:    float32(raster.NoDataValue),
+		HasNoData: raster.HasNoData,
+	}
+
+	for row := uint(0); row < h; row++ {
+		for col := uint(0); col < w; col++ {
+			z := raster.Z(col, row)
+			if !raster.IsNoData(col, row) {
+				z += elevationOffset
+			}
+
+			out.Data[row*w+col] = float32(z)
+		}
+	}
+
+	return out
+}
+
+// demGeoreference places the DEM at the position of its grid
+func demGeoreference(raster dem.EsriASCIIRaster) geotiff.Georeference {
+	return geotiff.Georeference{
+		OriginX:   raster.X(0),
+		OriginY:   raster.Y(0),
+		PixelSize: raster.CellSize,
+	}
+}
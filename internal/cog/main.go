@@ -0,0 +1,97 @@
+package cog
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"time"
+
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+	"github.com/gruppe-adler/meh-utils/internal/geotiff"
+	"github.com/gruppe-adler/meh-utils/internal/metajson"
+	"github.com/gruppe-adler/meh-utils/internal/sat"
+	"github.com/gruppe-adler/meh-utils/internal/validate"
+)
+
+// Run is the program's entrypoint
+func Run(flagSet *flag.FlagSet) {
+
+	var timer time.Time
+	start := time.Now()
+
+	inputPtr := flagSet.String("in", "", "Path to grad_meh map directory")
+	demOutPtr := flagSet.String("dem_out", "", "Path to output file for the DEM (not exported if empty)")
+	satOutPtr := flagSet.String("sat_out", "", "Path to output file for the satellite image (not exported if empty)")
+
+	flagSet.Parse(os.Args[2:])
+
+	// make sure the input and at least one output are present
+	if *inputPtr == "" || (*demOutPtr == "" && *satOutPtr == "") {
+		flagSet.PrintDefaults()
+		os.Exit(1)
+	}
+
+	// validate input directory structure
+	err := validate.MehDirectory(*inputPtr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("✔️  Validated input directory structure")
+
+	// load meta.json
+	timer = time.Now()
+	fmt.Println("▶️  Loading meta.json")
+	meta, err := metajson.Read(path.Join(*inputPtr, "meta.json"))
+	if err != nil {
+		log.Fatal(errors.New("Failed to read meta.json"))
+	}
+	fmt.Println("✔️  Loaded meta.json in", time.Now().Sub(timer).String())
+
+	if *demOutPtr != "" {
+		// load DEM
+		timer = time.Now()
+		fmt.Println("▶️  Loading DEM")
+		raster := dem.Read(path.Join(*inputPtr, "dem.asc.gz"))
+		fmt.Println("✔️  Loaded DEM in", time.Now().Sub(timer).String())
+
+		// write DEM
+		timer = time.Now()
+		fmt.Println("▶️  Writing DEM GeoTIFF")
+		err = geotiff.WriteFloat32(*demOutPtr, demRaster(raster, meta.ElevationOffset), demGeoreference(raster))
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("✔️  Wrote DEM GeoTIFF in", time.Now().Sub(timer).String())
+	}
+
+	if *satOutPtr != "" {
+		// combine sat image
+		timer = time.Now()
+		fmt.Println("▶️  Combining satellite image")
+		satImg, err := sat.CombineImage(path.Join(*inputPtr, "sat"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("✔️  Combined satellite image in", time.Now().Sub(timer).String())
+
+		// write sat image, which covers the whole world
+		timer = time.Now()
+		fmt.Println("▶️  Writing satellite GeoTIFF")
+		geo := geotiff.Georeference{
+			OriginX:   0,
+			OriginY:   meta.WorldSize,
+			PixelSize: meta.WorldSize / float64(satImg.Bounds().Dx()),
+		}
+		err = geotiff.WriteRGBA(*satOutPtr, satImg, geo)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("✔️  Wrote satellite GeoTIFF in", time.Now().Sub(timer).String())
+	}
+
+	fmt.Printf("\n    🎉  Finished in %s\n", time.Now().Sub(start).String())
+}
@@ -0,0 +1,146 @@
+package colorramp
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Stop is the color of a ramp at a value. Colors are not alpha-premultiplied.
+type Stop struct {
+	Value float64
+	Color color.NRGBA
+}
+
+// Ramp maps values to colors by interpolating linearly between its stops
+type Ramp struct {
+	Stops []Stop
+
+	// Discrete ramps don't interpolate. Every value gets the color of the last stop below or at it.
+	Discrete bool
+
+	// NoData is the color of cells without data, if HasNoData is set
+	NoData    color.NRGBA
+	HasNoData bool
+}
+
+// Parse reads a color ramp in the format of gdaldem color-relief. Every line is a
+// stop consisting of a value and the red, green, blue and optional alpha component
+// (0 - 255), separated by whitespace or commas. The value "nv" sets the color for
+// cells without data. Empty lines and lines starting with # are ignored.
+func Parse(reader io.Reader) (Ramp, error) {
+	ramp := Ramp{}
+
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+
+		if len(fields) != 4 && len(fields) != 5 {
+			return ramp, fmt.Errorf("Line %d of color ramp doesn't have 4 or 5 values", lineNumber)
+		}
+
+		components := []uint8{0, 0, 0, 255}
+		for i, field := range fields[1:] {
+			c, err := strconv.ParseUint(field, 10, 8)
+			if err != nil {
+				return ramp, fmt.Errorf("Line %d of color ramp has an invalid color component: %s", lineNumber, field)
+			}
+			components[i] = uint8(c)
+		}
+		c := color.NRGBA{components[0], components[1], components[2], components[3]}
+
+		if strings.ToLower(fields[0]) == "nv" {
+			ramp.NoData = c
+			ramp.HasNoData = true
+			continue
+		}
+
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return ramp, fmt.Errorf("Line %d of color ramp has an invalid value: %s", lineNumber, fields[0])
+		}
+
+		ramp.Stops = append(ramp.Stops, Stop{value, c})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return ramp, err
+	}
+
+	if len(ramp.Stops) == 0 {
+		return ramp, fmt.Errorf("Color ramp doesn't have any stops")
+	}
+
+	sort.SliceStable(ramp.Stops, func(i, j int) bool {
+		return ramp.Stops[i].Value < ramp.Stops[j].Value
+	})
+
+	return ramp, nil
+}
+
+// MustParse parses a color ramp from a string and panics if it is invalid. It is meant for built-in ramps.
+func MustParse(ramp string) Ramp {
+	r, err := Parse(strings.NewReader(ramp))
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Read a color ramp from given path
+func Read(filePath string) (Ramp, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return Ramp{}, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+// Color returns the color of given value. Values outside of the ramp get the color of the first / last stop.
+func (r Ramp) Color(value float64) color.NRGBA {
+	// index of the first stop with a larger value
+	i := sort.Search(len(r.Stops), func(i int) bool {
+		return r.Stops[i].Value > value
+	})
+
+	if i == 0 {
+		return r.Stops[0].Color
+	}
+	if i == len(r.Stops) || r.Discrete {
+		return r.Stops[i-1].Color
+	}
+
+	lower := r.Stops[i-1]
+	upper := r.Stops[i]
+	t := (value - lower.Value) / (upper.Value - lower.Value)
+
+	lerp := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + t*(float64(b)-float64(a))))
+	}
+
+	return color.NRGBA{
+		lerp(lower.Color.R, upper.Color.R),
+		lerp(lower.Color.G, upper.Color.G),
+		lerp(lower.Color.B, upper.Color.B),
+		lerp(lower.Color.A, upper.Color.A),
+	}
+}
@@ -0,0 +1,77 @@
+package colorramp
+
+import "math"
+
+// Elevation is a hypsometric tint for elevations above sea level
+var Elevation = MustParse(`
+# elevation   r   g   b
+0             172 208 165
+50            148 191 139
+100           168 198 143
+200           189 204 150
+300           209 215 171
+400           225 228 181
+500           239 235 192
+700           232 225 182
+900           222 214 163
+1200          211 202 157
+1500          202 185 130
+2000          195 167 107
+2500          185 152 90
+3000          170 135 83
+`)
+
+// Bathymetry is a tint for depths below sea level
+var Bathymetry = MustParse(`
+# elevation   r   g   b
+-6000         113 171 216
+-2000         132 185 227
+-500          150 201 240
+-200          161 210 247
+-50           172 219 251
+-10           185 227 255
+0             198 236 255
+`)
+
+// SlopeDegrees classifies slopes in degrees by how passable they are
+var SlopeDegrees = discrete(MustParse(`
+# slope       r   g   b
+0             26  150 65
+5             166 217 106
+15            255 255 191
+25            253 174 97
+35            215 25  28
+45            94  0   40
+`))
+
+// SlopePercent is SlopeDegrees with the slopes in percent
+var SlopePercent = discrete(Ramp{Stops: degreesToPercent(SlopeDegrees.Stops)})
+
+// Aspect classifies the direction a slope faces into the eight compass directions.
+// Flat cells (-1) are gray.
+var Aspect = discrete(MustParse(`
+# aspect      r   g   b
+-1            200 200 200
+0             255 0   0
+22.5          255 166 0
+67.5          255 255 0
+112.5         0   255 0
+157.5         0   255 255
+202.5         0   166 255
+247.5         0   0   255
+292.5         255 0   255
+337.5         255 0   0
+`))
+
+func discrete(ramp Ramp) Ramp {
+	ramp.Discrete = true
+	return ramp
+}
+
+func degreesToPercent(stops []Stop) []Stop {
+	percent := make([]Stop, len(stops))
+	for i, stop := range stops {
+		percent[i] = Stop{math.Tan(stop.Value*math.Pi/180) * 100, stop.Color}
+	}
+	return percent
+}
@@ -0,0 +1,39 @@
+package colorrelief
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/gruppe-adler/meh-utils/internal/colorramp"
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+)
+
+func calculateImage(raster dem.EsriASCIIRaster, elevationOffset float64, elevation colorramp.Ramp, bathymetry colorramp.Ramp) *image.RGBA {
+	w, h := raster.Dims()
+
+	img := image.NewRGBA(image.Rectangle{image.Point{0, 0}, image.Point{int(w), int(h)}})
+
+	for col := uint(0); col < w; col++ {
+		for row := uint(0); row < h; row++ {
+			z := raster.Z(col, row)
+
+			var c color.NRGBA
+			switch {
+			case raster.IsNoData(col, row):
+				// cells without data stay transparent, unless the ramp has a color for them
+				if !elevation.HasNoData {
+					continue
+				}
+				c = elevation.NoData
+			case z+elevationOffset < 0:
+				c = bathymetry.Color(z + elevationOffset)
+			default:
+				c = elevation.Color(z + elevationOffset)
+			}
+
+			img.Set(int(col), int(row), c)
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,114 @@
+package colorrelief
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gruppe-adler/meh-utils/internal/colorramp"
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+	"github.com/gruppe-adler/meh-utils/internal/metajson"
+	"github.com/gruppe-adler/meh-utils/internal/tilejson"
+	"github.com/gruppe-adler/meh-utils/internal/tilewriter"
+	"github.com/gruppe-adler/meh-utils/internal/utils"
+	"github.com/gruppe-adler/meh-utils/internal/validate"
+)
+
+// Run is the program's entrypoint
+func Run(flagSet *flag.FlagSet) {
+
+	var timer time.Time
+	start := time.Now()
+
+	outputPtr := flagSet.String("out", "", "Path to output directory (or output file for -format mbtiles / pmtiles)")
+	inputPtr := flagSet.String("in", "", "Path to grad_meh map directory")
+	formatPtr := flagSet.String("format", tilewriter.Directory, fmt.Sprintf("Output format (%s)", strings.Join(tilewriter.Formats, ", ")))
+	rampPtr := flagSet.String("ramp", "", "Path to color ramp for elevations above sea level (gdaldem color-relief format, built-in ramp if empty)")
+	bathymetryPtr := flagSet.String("bathymetry", "", "Path to color ramp for elevations below sea level (gdaldem color-relief format, built-in ramp if empty)")
+
+	flagSet.Parse(os.Args[2:])
+
+	// make sure both flags are present
+	if *outputPtr == "" || *inputPtr == "" {
+		flagSet.PrintDefaults()
+		os.Exit(1)
+	}
+
+	// create tile writer (makes sure the output path is valid)
+	writer, err := tilewriter.New(*formatPtr, *outputPtr, "png")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// load color ramps
+	elevationRamp := colorramp.Elevation
+	if *rampPtr != "" {
+		elevationRamp, err = colorramp.Read(*rampPtr)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	bathymetryRamp := colorramp.Bathymetry
+	if *bathymetryPtr != "" {
+		bathymetryRamp, err = colorramp.Read(*bathymetryPtr)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// validate input directory structure
+	err = validate.MehDirectory(*inputPtr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("✔️  Validated input directory structure")
+
+	// load meta.json
+	timer = time.Now()
+	fmt.Println("▶️  Loading meta.json")
+	meta, err := metajson.Read(path.Join(*inputPtr, "meta.json"))
+	if err != nil {
+		log.Fatal(errors.New("Failed to read meta.json"))
+	}
+	fmt.Println("✔️  Loaded meta.json in", time.Now().Sub(timer).String())
+
+	// load DEM
+	timer = time.Now()
+	fmt.Println("▶️  Loading DEM")
+	raster := dem.Read(path.Join(*inputPtr, "dem.asc.gz"))
+	fmt.Println("✔️  Loaded DEM in", time.Now().Sub(timer).String())
+
+	// calculating image
+	timer = time.Now()
+	fmt.Println("▶️  Calculating color relief from DEM")
+	img := calculateImage(raster, meta.ElevationOffset, elevationRamp, bathymetryRamp)
+	fmt.Println("✔️  Calculated color relief in", time.Now().Sub(timer).String())
+
+	// calculate max LOD
+	maxLod := utils.CalcMaxLodFromImage(img)
+	fmt.Println("ℹ️  Calculated max lod:", maxLod)
+
+	// build tiles
+	timer = time.Now()
+	fmt.Println("▶️  Building tiles")
+	utils.BuildTilePyramid(maxLod, img, utils.RGBAResampler{}, writer)
+	fmt.Println("✔️  Built color relief tiles in", time.Now().Sub(timer).String())
+
+	// write tile.json / metadata
+	timer = time.Now()
+	fmt.Println("▶️  Writing tileset metadata")
+	err = writer.Close(tilejson.New(maxLod, meta, "Color Relief", []string{}))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("✔️  Wrote tileset metadata in", time.Now().Sub(timer).String())
+
+	fmt.Printf("\n    🎉  Finished in %s\n", time.Now().Sub(start).String())
+}
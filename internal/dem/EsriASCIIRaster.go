@@ -6,8 +6,13 @@ type EsriASCIIRaster struct {
 	Xcenter, Ycenter *float64
 	Xcorner, Ycorner *float64
 	CellSize         float64
-	NoDataValue      float64
-	Data             [][]float64
+
+	// NoDataValue marks cells without data, if HasNoData is set
+	NoDataValue float64
+	HasNoData   bool
+
+	// Data holds the values row by row, starting in the top left corner
+	Data []float32
 }
 
 // Dims returns the dimensions of the grid.
@@ -18,7 +23,19 @@ func (raster EsriASCIIRaster) Dims() (c, r uint) {
 // Z returns the value of a grid value at (c, r).
 // It will panic if c or r are out of bounds for the grid.
 func (raster EsriASCIIRaster) Z(c, r uint) float64 {
-	return raster.Data[r][c]
+	return float64(raster.Data[r*raster.Ncols+c])
+}
+
+// IsNoData checks whether the grid value at (c, r) is the NoDataValue.
+// It will panic if c or r are out of bounds for the grid.
+func (raster EsriASCIIRaster) IsNoData(c, r uint) bool {
+	return raster.HasNoData && raster.Data[r*raster.Ncols+c] == float32(raster.NoDataValue)
+}
+
+// Set changes the grid value at (c, r).
+// It will panic if c or r are out of bounds for the grid.
+func (raster EsriASCIIRaster) Set(c, r uint, z float64) {
+	raster.Data[r*raster.Ncols+c] = float32(z)
 }
 
 // X returns the coordinate for the column at the index c.
@@ -0,0 +1,94 @@
+package dem
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format is a file format digital elevation models can be read from
+type Format interface {
+	// Name of the format
+	Name() string
+
+	// Match reports whether the file at given path is in this format. It is called with the
+	// path and the first bytes of the file, so formats can be detected by extension or magic bytes.
+	Match(filePath string, head []byte) bool
+
+	// Decode reads the file at given path
+	Decode(filePath string) (EsriASCIIRaster, error)
+}
+
+// Formats are all supported formats in the order in which they are matched
+var Formats = []Format{
+	esriASCIIGzipFormat{},
+	geoTIFFFormat{},
+	esriFloatFormat{},
+	esriASCIIFormat{},
+	xyzFormat{},
+}
+
+// number of bytes which are passed to Format.Match
+const headSize = 512
+
+// DetectFormat finds the format of the file at given path
+func DetectFormat(filePath string) (Format, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	head := make([]byte, headSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	for _, format := range Formats {
+		if format.Match(filePath, head) {
+			return format, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s is not in a supported DEM format", filePath)
+}
+
+// Load reads a digital elevation model in any of the supported formats
+func Load(filePath string) (EsriASCIIRaster, error) {
+	format, err := DetectFormat(filePath)
+	if err != nil {
+		return EsriASCIIRaster{}, err
+	}
+
+	raster, err := format.Decode(filePath)
+	if err != nil {
+		return raster, fmt.Errorf("%s (%s): %s", filePath, format.Name(), err)
+	}
+
+	return raster, nil
+}
+
+// hasExtension checks case-insensitively whether the file path ends with any of the extensions
+func hasExtension(filePath string, extensions ...string) bool {
+	lower := strings.ToLower(filepath.Base(filePath))
+
+	for _, ext := range extensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// startsWithKeyword checks whether the first non-whitespace characters are given keyword (case-insensitive)
+func startsWithKeyword(head []byte, keyword string) bool {
+	head = bytes.TrimLeft(head, " \t\r\n")
+
+	return len(head) >= len(keyword) && strings.EqualFold(string(head[:len(keyword)]), keyword)
+}
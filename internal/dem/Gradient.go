@@ -0,0 +1,51 @@
+package dem
+
+// Gradient returns the rate of change of the height towards east (dzdx) and north (dzdy)
+// at the cell (c, r). It is calculated with Horn's method from the 3x3 neighborhood of the
+// cell. At the edges of the grid the missing neighbors are replaced by the cell itself, just
+// like neighbors without data. Cells without data are flat.
+func (raster EsriASCIIRaster) Gradient(c, r uint) (dzdx, dzdy float64) {
+	if raster.IsNoData(c, r) {
+		return 0, 0
+	}
+
+	left, right := c, c
+	if c > 0 {
+		left = c - 1
+	}
+	if c+1 < raster.Ncols {
+		right = c + 1
+	}
+
+	top, bottom := r, r
+	if r > 0 {
+		top = r - 1
+	}
+	if r+1 < raster.Nrows {
+		bottom = r + 1
+	}
+
+	z := func(col, row uint) float64 {
+		if raster.IsNoData(col, row) {
+			return raster.Z(c, r)
+		}
+		return raster.Z(col, row)
+	}
+
+	// a b c
+	// d e f
+	// g h i
+	a, b, cc := z(left, top), z(c, top), z(right, top)
+	d, f := z(left, r), z(right, r)
+	g, h, i := z(left, bottom), z(c, bottom), z(right, bottom)
+
+	// the distance between the neighbors is smaller at the edges
+	if right > left {
+		dzdx = ((cc + 2*f + i) - (a + 2*d + g)) / (4 * float64(right-left) * raster.CellSize)
+	}
+	if bottom > top {
+		dzdy = ((a + 2*b + cc) - (g + 2*h + i)) / (4 * float64(bottom-top) * raster.CellSize)
+	}
+
+	return dzdx, dzdy
+}
@@ -2,31 +2,41 @@ package dem
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
+	"runtime"
 	"strconv"
 	"strings"
 )
 
-// ParseEsriASCIIRaster does shizzlze
+// ParseEsriASCIIRaster parses an ESRI ASCII grid. The header is read line by line and the
+// values are read in chunks, which are parsed concurrently.
 func ParseEsriASCIIRaster(reader io.Reader) (EsriASCIIRaster, error) {
 
 	raster := EsriASCIIRaster{}
 	remainingHeaders := []string{"NCOLS", "NROWS", "XLLCENTER", "XLLCORNER", "YLLCENTER", "YLLCORNER", "CELLSIZE", "NODATA_VALUE"}
-	stillIsHeader := true
-	rowIndex := uint(0)
-	var esriData [][]float64
+	buffered := bufio.NewReader(reader)
 
-	scanner := bufio.NewScanner(reader)
+	// the first line, which isn't a header, is already part of the data
+	var firstDataLine []byte
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		fields := strings.Fields(line)
+	for {
+		line, err := buffered.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return raster, err
+		}
 
-		// first field as upper case
-		keyword := strings.ToUpper(fields[0])
+		fields := strings.Fields(string(line))
+		if len(fields) > 0 {
+			// first field as upper case
+			keyword := strings.ToUpper(fields[0])
+
+			if !contains(remainingHeaders, keyword) {
+				firstDataLine = line
+				break
+			}
 
-		if stillIsHeader && contains(remainingHeaders, keyword) {
 			remainingHeaders = remove(remainingHeaders, keyword)
 
 			// there can either be corner or center not both
@@ -40,43 +50,202 @@ func ParseEsriASCIIRaster(reader io.Reader) (EsriASCIIRaster, error) {
 			}
 
 			err := parseHeaderLine(fields, &raster)
-
 			if err != nil {
 				return raster, err
 			}
-		} else {
-			if stillIsHeader { // this is the first data line, if stillIsHeader is true
-				// we're just going to remove the NODATA_VALUE if it is still present, because it's a optional header
-				remainingHeaders = remove(remainingHeaders, "NODATA_VALUE")
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	// we're just going to remove the NODATA_VALUE if it is still present, because it's a optional header
+	remainingHeaders = remove(remainingHeaders, "NODATA_VALUE")
+
+	if len(remainingHeaders) > 0 {
+		return raster, fmt.Errorf("DEM doesn't include all mandatory headers")
+	}
+
+	raster.Data = make([]float32, raster.Ncols*raster.Nrows)
+
+	n, err := parseValues(io.MultiReader(bytes.NewReader(firstDataLine), buffered), raster.Data)
+	if err != nil {
+		return raster, err
+	}
+	if n < len(raster.Data) {
+		return raster, fmt.Errorf("DEM data is too short")
+	}
+
+	return raster, nil
+}
+
+// size of the chunks in which values are parsed concurrently
+const parseChunkSize = 1 << 22
+
+type valueChunk struct {
+	data   []byte
+	result chan valueChunkResult
+}
 
-				if len(remainingHeaders) > 0 {
-					return raster, fmt.Errorf("DEM doesn't include all mandatory headers")
+type valueChunkResult struct {
+	values []float32
+	err    error
+}
+
+// parseValues parses whitespace separated values from the reader into dst. The reader is
+// split into chunks, which are parsed by one worker per CPU and then copied into dst in order.
+// Only a few chunks are in memory at once. It returns the number of values in dst.
+func parseValues(reader io.Reader, dst []float32) (int, error) {
+	workers := runtime.NumCPU()
+
+	// jobs are parsed in any order, pending are the same chunks in the order of the reader
+	jobs := make(chan *valueChunk, workers)
+	pending := make(chan *valueChunk, 2*workers)
+	readErr := make(chan error, 1)
+
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+
+		var rest []byte
+		for {
+			data := make([]byte, len(rest)+parseChunkSize)
+			copy(data, rest)
+			n, err := io.ReadFull(reader, data[len(rest):])
+			data = data[:len(rest)+n]
+			rest = nil
+
+			// don't split a value between two chunks
+			if err == nil {
+				if cut := bytes.LastIndexAny(data, " \t\r\n"); cut >= 0 {
+					rest = append(rest, data[cut+1:]...)
+					data = data[:cut+1]
 				}
+			}
+
+			if len(data) > 0 {
+				chunk := &valueChunk{data: data, result: make(chan valueChunkResult, 1)}
+				jobs <- chunk
+				pending <- chunk
+			}
 
-				stillIsHeader = false
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				readErr <- nil
+				return
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
 
-				esriData = make([][]float64, raster.Nrows)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for chunk := range jobs {
+				values, err := parseChunk(chunk.data)
+				chunk.result <- valueChunkResult{values, err}
 			}
+		}()
+	}
 
-			row, err := parseDataLine(fields, raster.Ncols)
+	// collect results in order, but keep draining after an error, so that the reader can finish
+	n := 0
+	var err error
+	for chunk := range pending {
+		result := <-chunk.result
+		if err != nil {
+			continue
+		}
+		if result.err != nil {
+			err = result.err
+			continue
+		}
+
+		n += copy(dst[n:], result.values)
+	}
+
+	if err != nil {
+		return n, err
+	}
+
+	return n, <-readErr
+}
+
+// parseChunk parses all whitespace separated values of the chunk
+func parseChunk(data []byte) ([]float32, error) {
+	values := make([]float32, 0, len(data)/4)
+
+	start := -1
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && data[i] != ' ' && data[i] != '\t' && data[i] != '\r' && data[i] != '\n' {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+
+		if start >= 0 {
+			value, err := parseValue(data[start:i])
 			if err != nil {
-				return raster, err
+				return values, err
 			}
+			values = append(values, value)
+			start = -1
+		}
+	}
 
-			esriData[rowIndex] = row
-			rowIndex++
+	return values, nil
+}
 
-			if rowIndex >= raster.Nrows {
-				break
+// parseValue parses a single value. Plain decimals (e.g. -12.345) are parsed directly,
+// everything else (e.g. exponents) is passed to strconv.
+func parseValue(field []byte) (float32, error) {
+	i := 0
+	negative := false
+	if field[0] == '-' || field[0] == '+' {
+		negative = field[0] == '-'
+		i++
+	}
+
+	var mantissa uint64
+	digits, decimals := 0, 0
+	dot := false
+	for ; i < len(field); i++ {
+		ch := field[i]
+		if ch >= '0' && ch <= '9' {
+			mantissa = mantissa*10 + uint64(ch-'0')
+			digits++
+			if dot {
+				decimals++
 			}
+		} else if ch == '.' && !dot {
+			dot = true
+		} else {
+			break
 		}
 	}
 
-	raster.Data = esriData
+	// fall back to strconv for anything which can't be parsed exactly
+	if i < len(field) || digits == 0 || digits > 15 {
+		f, err := strconv.ParseFloat(string(field), 32)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid DEM value: %s", field)
+		}
+		return float32(f), nil
+	}
 
-	return raster, nil
+	value := float64(mantissa) / pow10[decimals]
+	if negative {
+		value = -value
+	}
+
+	return float32(value), nil
 }
 
+var pow10 = [...]float64{1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10, 1e11, 1e12, 1e13, 1e14, 1e15}
+
 func parseHeaderLine(fields []string, grid *EsriASCIIRaster) error {
 	if len(fields) != 2 {
 		return fmt.Errorf("Header line must have excatly two fields")
@@ -138,6 +307,7 @@ func parseHeaderLine(fields []string, grid *EsriASCIIRaster) error {
 			return err
 		}
 		(*grid).NoDataValue = f
+		(*grid).HasNoData = true
 
 	default:
 		return fmt.Errorf("Unknown header keyword: %s", fields[0])
@@ -146,24 +316,6 @@ func parseHeaderLine(fields []string, grid *EsriASCIIRaster) error {
 	return nil
 }
 
-func parseDataLine(fields []string, cols uint) ([]float64, error) {
-	row := make([]float64, cols)
-
-	if uint(len(fields)) < cols {
-		return row, fmt.Errorf("DEM data row is too short")
-	}
-
-	for i := uint(0); i < cols; i++ {
-		f, err := strconv.ParseFloat(fields[i], 64)
-		if err != nil {
-			return row, err
-		}
-		row[i] = f
-	}
-
-	return row, nil
-}
-
 // contains checks whether an array contains a string
 func contains(array []string, element string) bool {
 	for _, curElement := range array {
@@ -0,0 +1,105 @@
+package dem
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ParseEsriFloatGrid parses a raw float32 grid with an ESRI sidecar header (.flt / .hdr).
+// The header has the same keywords as an ESRI ASCII grid and an optional BYTEORDER
+// (LSBFIRST or MSBFIRST). The data are the rows of the grid, starting at the top.
+func ParseEsriFloatGrid(header io.Reader, data io.Reader) (EsriASCIIRaster, error) {
+	raster := EsriASCIIRaster{}
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+
+	scanner := bufio.NewScanner(header)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if strings.ToUpper(fields[0]) == "BYTEORDER" {
+			if len(fields) != 2 {
+				return raster, fmt.Errorf("Header line must have excatly two fields")
+			}
+
+			switch strings.ToUpper(fields[1]) {
+			case "LSBFIRST", "I":
+				byteOrder = binary.LittleEndian
+			case "MSBFIRST", "M":
+				byteOrder = binary.BigEndian
+			default:
+				return raster, fmt.Errorf("Unknown byte order: %s", fields[1])
+			}
+			continue
+		}
+
+		err := parseHeaderLine(fields, &raster)
+		if err != nil {
+			return raster, err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return raster, err
+	}
+
+	if raster.Ncols == 0 || raster.Nrows == 0 || raster.CellSize == 0 {
+		return raster, fmt.Errorf("DEM doesn't include all mandatory headers")
+	}
+	if (raster.Xcorner == nil && raster.Xcenter == nil) || (raster.Ycorner == nil && raster.Ycenter == nil) {
+		return raster, fmt.Errorf("DEM doesn't include all mandatory headers")
+	}
+
+	row := make([]byte, 4*raster.Ncols)
+	raster.Data = make([]float32, raster.Ncols*raster.Nrows)
+
+	for r := uint(0); r < raster.Nrows; r++ {
+		if _, err := io.ReadFull(data, row); err != nil {
+			return raster, fmt.Errorf("DEM data is too short: %s", err)
+		}
+
+		values := raster.Data[r*raster.Ncols : (r+1)*raster.Ncols]
+		for c := range values {
+			values[c] = math.Float32frombits(byteOrder.Uint32(row[4*c:]))
+		}
+	}
+
+	return raster, nil
+}
+
+// esriFloatFormat is a raw float32 grid, whose header is in a file with the same name and the extension .hdr
+type esriFloatFormat struct{}
+
+func (esriFloatFormat) Name() string {
+	return "ESRI float grid"
+}
+
+func (esriFloatFormat) Match(filePath string, head []byte) bool {
+	return hasExtension(filePath, ".flt")
+}
+
+func (esriFloatFormat) Decode(filePath string) (EsriASCIIRaster, error) {
+	headerPath := strings.TrimSuffix(filePath, filepath.Ext(filePath)) + ".hdr"
+
+	header, err := os.Open(headerPath)
+	if err != nil {
+		return EsriASCIIRaster{}, err
+	}
+	defer header.Close()
+
+	data, err := os.Open(filePath)
+	if err != nil {
+		return EsriASCIIRaster{}, err
+	}
+	defer data.Close()
+
+	return ParseEsriFloatGrid(header, bufio.NewReader(data))
+}
@@ -0,0 +1,389 @@
+package dem
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// TIFF tags used to read a GeoTIFF DEM
+const (
+	tiffTagImageWidth      = 256
+	tiffTagImageLength     = 257
+	tiffTagBitsPerSample   = 258
+	tiffTagCompression     = 259
+	tiffTagStripOffsets    = 273
+	tiffTagSamplesPerPixel = 277
+	tiffTagRowsPerStrip    = 278
+	tiffTagStripByteCounts = 279
+	tiffTagPredictor       = 317
+	tiffTagTileWidth       = 322
+	tiffTagTileLength      = 323
+	tiffTagTileOffsets     = 324
+	tiffTagTileByteCounts  = 325
+	tiffTagSampleFormat    = 339
+	tiffTagPixelScale      = 33550
+	tiffTagTiepoint        = 33922
+	tiffTagGeoKeyDirectory = 34735
+	tiffTagGDALNoData      = 42113
+
+	geoKeyRasterType   = 1025
+	rasterPixelIsPoint = 2
+)
+
+// tiffField is the value of a TIFF tag
+type tiffField struct {
+	numbers []float64
+	text    string
+}
+
+// ParseGeoTIFF parses the first image of a single band 16 or 32 bit integer or float GeoTIFF or
+// BigTIFF. The image can be stored in strips or tiles, either uncompressed or deflated. The grid is
+// placed by the ModelTiepoint and ModelPixelScale tags and NoData is read from the GDAL_NODATA tag.
+func ParseGeoTIFF(data []byte) (EsriASCIIRaster, error) {
+	raster := EsriASCIIRaster{}
+
+	if len(data) < 8 {
+		return raster, fmt.Errorf("TIFF is too short")
+	}
+
+	var order binary.ByteOrder
+	switch string(data[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return raster, fmt.Errorf("Not a TIFF")
+	}
+
+	var firstIFD uint64
+	bigTIFF := false
+	switch order.Uint16(data[2:]) {
+	case 42:
+		firstIFD = uint64(order.Uint32(data[4:]))
+	case 43:
+		// BigTIFF has 8 byte offsets
+		if len(data) < 16 || order.Uint16(data[4:]) != 8 {
+			return raster, fmt.Errorf("Invalid BigTIFF header")
+		}
+		firstIFD = order.Uint64(data[8:])
+		bigTIFF = true
+	default:
+		return raster, fmt.Errorf("Not a TIFF")
+	}
+
+	fields, err := readTIFFDirectory(data, order, firstIFD, bigTIFF)
+	if err != nil {
+		return raster, err
+	}
+
+	number := func(tag uint16, defaultValue float64) float64 {
+		if f, found := fields[tag]; found && len(f.numbers) > 0 {
+			return f.numbers[0]
+		}
+		return defaultValue
+	}
+
+	width := int(number(tiffTagImageWidth, 0))
+	height := int(number(tiffTagImageLength, 0))
+	bitsPerSample := int(number(tiffTagBitsPerSample, 1))
+	sampleFormat := int(number(tiffTagSampleFormat, 1))
+	compression := int(number(tiffTagCompression, 1))
+	predictor := int(number(tiffTagPredictor, 1))
+
+	if width == 0 || height == 0 {
+		return raster, fmt.Errorf("TIFF doesn't have dimensions")
+	}
+	if number(tiffTagSamplesPerPixel, 1) != 1 {
+		return raster, fmt.Errorf("TIFF must have exactly one band")
+	}
+	if bitsPerSample != 16 && bitsPerSample != 32 {
+		return raster, fmt.Errorf("%d bits per sample are not supported", bitsPerSample)
+	}
+	if sampleFormat < 1 || sampleFormat > 3 || (sampleFormat == 3 && bitsPerSample != 32) {
+		return raster, fmt.Errorf("Sample format %d with %d bits is not supported", sampleFormat, bitsPerSample)
+	}
+	if compression != 1 && compression != 8 && compression != 32946 {
+		return raster, fmt.Errorf("Compression %d is not supported", compression)
+	}
+	if predictor != 1 && (predictor != 2 || sampleFormat == 3) {
+		return raster, fmt.Errorf("Predictor %d is not supported", predictor)
+	}
+
+	sample := func(b []byte) float64 {
+		switch {
+		case bitsPerSample == 16 && sampleFormat == 2:
+			return float64(int16(order.Uint16(b)))
+		case bitsPerSample == 16:
+			return float64(order.Uint16(b))
+		case sampleFormat == 2:
+			return float64(int32(order.Uint32(b)))
+		case sampleFormat == 3:
+			return float64(math.Float32frombits(order.Uint32(b)))
+		default:
+			return float64(order.Uint32(b))
+		}
+	}
+
+	// strips are tiles with the width of the image
+	chunkWidth, chunkHeight := width, int(number(tiffTagRowsPerStrip, float64(height)))
+	offsets, byteCounts := fields[tiffTagStripOffsets].numbers, fields[tiffTagStripByteCounts].numbers
+	if _, tiled := fields[tiffTagTileOffsets]; tiled {
+		chunkWidth, chunkHeight = int(number(tiffTagTileWidth, 0)), int(number(tiffTagTileLength, 0))
+		offsets, byteCounts = fields[tiffTagTileOffsets].numbers, fields[tiffTagTileByteCounts].numbers
+	}
+	if chunkWidth <= 0 || chunkHeight <= 0 || len(offsets) == 0 || len(offsets) != len(byteCounts) {
+		return raster, fmt.Errorf("TIFF has an invalid strip / tile layout")
+	}
+	chunkHeight = minInt(chunkHeight, height)
+
+	chunksPerRow := (width + chunkWidth - 1) / chunkWidth
+	chunkRows := (height + chunkHeight - 1) / chunkHeight
+	if len(offsets) < chunksPerRow*chunkRows {
+		return raster, fmt.Errorf("TIFF has too few strips / tiles")
+	}
+
+	raster.Ncols, raster.Nrows = uint(width), uint(height)
+	raster.Data = make([]float32, width*height)
+
+	sampleSize := bitsPerSample / 8
+	for i := 0; i < chunksPerRow*chunkRows; i++ {
+		start, end := int(offsets[i]), int(offsets[i])+int(byteCounts[i])
+		if start < 0 || end > len(data) || start > end {
+			return raster, fmt.Errorf("TIFF strip / tile %d is out of bounds", i)
+		}
+
+		chunk := data[start:end]
+		if compression != 1 {
+			chunk, err = inflate(chunk)
+			if err != nil {
+				return raster, err
+			}
+		}
+
+		if predictor == 2 {
+			undoHorizontalDifferencing(chunk, chunkWidth, sampleSize, order)
+		}
+
+		x0, y0 := (i%chunksPerRow)*chunkWidth, (i/chunksPerRow)*chunkHeight
+		for y := 0; y < chunkHeight && y0+y < height; y++ {
+			for x := 0; x < chunkWidth && x0+x < width; x++ {
+				offset := (y*chunkWidth + x) * sampleSize
+				if offset+sampleSize > len(chunk) {
+					return raster, fmt.Errorf("TIFF strip / tile %d is too short", i)
+				}
+				raster.Set(uint(x0+x), uint(y0+y), sample(chunk[offset:]))
+			}
+		}
+	}
+
+	// georeference
+	scaleX, scaleY := 1.0, 1.0
+	if f, found := fields[tiffTagPixelScale]; found && len(f.numbers) >= 2 {
+		scaleX, scaleY = f.numbers[0], f.numbers[1]
+	}
+	if scaleX != scaleY || scaleX <= 0 {
+		return raster, fmt.Errorf("Pixels must be square")
+	}
+	raster.CellSize = scaleX
+
+	var left, top float64
+	if f, found := fields[tiffTagTiepoint]; found && len(f.numbers) >= 6 {
+		left = f.numbers[3] - f.numbers[0]*scaleX
+		top = f.numbers[4] + f.numbers[1]*scaleY
+	} else {
+		top = float64(height) * scaleY
+	}
+
+	if f, found := fields[tiffTagGeoKeyDirectory]; found && geoKey(f.numbers, geoKeyRasterType) == rasterPixelIsPoint {
+		left -= scaleX / 2
+		top += scaleY / 2
+	}
+
+	bottom := top - float64(height)*scaleY
+	raster.Xcorner = &left
+	raster.Ycorner = &bottom
+
+	if f, found := fields[tiffTagGDALNoData]; found {
+		noData, err := strconv.ParseFloat(strings.TrimSpace(f.text), 64)
+		if err == nil {
+			raster.NoDataValue = noData
+			raster.HasNoData = true
+		}
+	}
+
+	return raster, nil
+}
+
+// readTIFFDirectory reads the fields of the image file directory at offset. BigTIFF directories
+// have 8 byte counts and offsets, so their entries are 20 instead of 12 bytes long.
+func readTIFFDirectory(data []byte, order binary.ByteOrder, offset uint64, bigTIFF bool) (map[uint16]tiffField, error) {
+	typeSizes := map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8, 16: 8, 17: 8, 18: 8}
+
+	countSize, entrySize, valueSize := 2, 12, 4
+	if bigTIFF {
+		countSize, entrySize, valueSize = 8, 20, 8
+	}
+
+	if offset+uint64(countSize) > uint64(len(data)) {
+		return nil, fmt.Errorf("TIFF directory is out of bounds")
+	}
+
+	var count uint64
+	if bigTIFF {
+		count = order.Uint64(data[offset:])
+	} else {
+		count = uint64(order.Uint16(data[offset:]))
+	}
+	if count > uint64(len(data)) || offset+uint64(countSize)+count*uint64(entrySize) > uint64(len(data)) {
+		return nil, fmt.Errorf("TIFF directory is out of bounds")
+	}
+
+	fields := make(map[uint16]tiffField, count)
+	for i := 0; i < int(count); i++ {
+		e := data[int(offset)+countSize+i*entrySize:]
+		tag, typ := order.Uint16(e), order.Uint16(e[2:])
+
+		var n uint64
+		if bigTIFF {
+			n = order.Uint64(e[4:])
+		} else {
+			n = uint64(order.Uint32(e[4:]))
+		}
+		valueField := e[entrySize-valueSize : entrySize]
+
+		size, known := typeSizes[typ]
+		if !known {
+			continue
+		}
+		if n > uint64(len(data)) {
+			return nil, fmt.Errorf("TIFF field %d is out of bounds", tag)
+		}
+
+		value := valueField
+		if uint64(size)*n > uint64(valueSize) {
+			var start uint64
+			if bigTIFF {
+				start = order.Uint64(valueField)
+			} else {
+				start = uint64(order.Uint32(valueField))
+			}
+			if start+uint64(size)*n > uint64(len(data)) {
+				return nil, fmt.Errorf("TIFF field %d is out of bounds", tag)
+			}
+			value = data[start : start+uint64(size)*n]
+		}
+
+		field := tiffField{}
+		if typ == 2 {
+			field.text = strings.TrimRight(string(value[:n]), "\x00")
+		} else {
+			field.numbers = make([]float64, n)
+			for j := range field.numbers {
+				v := value[j*size:]
+				switch typ {
+				case 1, 7:
+					field.numbers[j] = float64(v[0])
+				case 6:
+					field.numbers[j] = float64(int8(v[0]))
+				case 3:
+					field.numbers[j] = float64(order.Uint16(v))
+				case 8:
+					field.numbers[j] = float64(int16(order.Uint16(v)))
+				case 4:
+					field.numbers[j] = float64(order.Uint32(v))
+				case 9:
+					field.numbers[j] = float64(int32(order.Uint32(v)))
+				case 5:
+					field.numbers[j] = float64(order.Uint32(v)) / float64(order.Uint32(v[4:]))
+				case 10:
+					field.numbers[j] = float64(int32(order.Uint32(v))) / float64(int32(order.Uint32(v[4:])))
+				case 11:
+					field.numbers[j] = float64(math.Float32frombits(order.Uint32(v)))
+				case 12:
+					field.numbers[j] = math.Float64frombits(order.Uint64(v))
+				case 16, 18:
+					field.numbers[j] = float64(order.Uint64(v))
+				case 17:
+					field.numbers[j] = float64(int64(order.Uint64(v)))
+				}
+			}
+		}
+
+		fields[tag] = field
+	}
+
+	return fields, nil
+}
+
+// geoKey returns the value of a key from the GeoKeyDirectory, which is stored directly in the directory
+func geoKey(directory []float64, key uint16) float64 {
+	for i := 4; i+3 < len(directory); i += 4 {
+		if uint16(directory[i]) == key && directory[i+1] == 0 {
+			return directory[i+3]
+		}
+	}
+	return 0
+}
+
+// undoHorizontalDifferencing restores the samples of each row, which are stored as the difference to their left neighbor
+func undoHorizontalDifferencing(chunk []byte, width int, sampleSize int, order binary.ByteOrder) {
+	rowSize := width * sampleSize
+
+	for start := 0; start+rowSize <= len(chunk); start += rowSize {
+		row := chunk[start : start+rowSize]
+		for x := sampleSize; x < rowSize; x += sampleSize {
+			if sampleSize == 2 {
+				order.PutUint16(row[x:], order.Uint16(row[x:])+order.Uint16(row[x-2:]))
+			} else {
+				order.PutUint32(row[x:], order.Uint32(row[x:])+order.Uint32(row[x-4:]))
+			}
+		}
+	}
+}
+
+// inflate decompresses a deflated strip / tile
+func inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// geoTIFFFormat is a single band GeoTIFF
+type geoTIFFFormat struct{}
+
+func (geoTIFFFormat) Name() string {
+	return "GeoTIFF"
+}
+
+func (geoTIFFFormat) Match(filePath string, head []byte) bool {
+	return bytes.HasPrefix(head, []byte{'I', 'I', 42, 0}) || bytes.HasPrefix(head, []byte{'M', 'M', 0, 42}) ||
+		bytes.HasPrefix(head, []byte{'I', 'I', 43, 0}) || bytes.HasPrefix(head, []byte{'M', 'M', 0, 43}) ||
+		hasExtension(filePath, ".tif", ".tiff")
+}
+
+func (geoTIFFFormat) Decode(filePath string) (EsriASCIIRaster, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return EsriASCIIRaster{}, err
+	}
+
+	return ParseGeoTIFF(data)
+}
@@ -0,0 +1,180 @@
+package dem
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// xyzNoDataValue is used for grid points which aren't part of an XYZ point list
+const xyzNoDataValue = -9999
+
+// ParseXYZ parses a list of "x y z" points on a regular grid, like the XYZ heightmaps
+// exported by Terrain Builder. Values can be separated by whitespace, commas or semicolons.
+// Lines which don't start with a number (e.g. a header) are ignored. The cell size is the
+// most common step between the coordinates and points which aren't on the grid are rejected.
+// Grid points which aren't in the list get the NoData value.
+func ParseXYZ(reader io.Reader) (EsriASCIIRaster, error) {
+	raster := EsriASCIIRaster{}
+
+	type point struct{ x, y, z float64 }
+	var points []point
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.FieldsFunc(scanner.Text(), func(r rune) bool {
+			return r == ',' || r == ';' || r == ' ' || r == '\t'
+		})
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		if _, err := strconv.ParseFloat(fields[0], 64); err != nil {
+			continue
+		}
+
+		if len(fields) < 3 {
+			return raster, fmt.Errorf("Line %d doesn't have x, y and z values", lineNumber)
+		}
+
+		var p [3]float64
+		for i := range p {
+			f, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return raster, fmt.Errorf("Line %d has an invalid value: %s", lineNumber, fields[i])
+			}
+			p[i] = f
+		}
+
+		points = append(points, point{p[0], p[1], p[2]})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return raster, err
+	}
+
+	if len(points) == 0 {
+		return raster, fmt.Errorf("XYZ doesn't have any points")
+	}
+
+	xs := make([]float64, len(points))
+	ys := make([]float64, len(points))
+	for i, p := range points {
+		xs[i], ys[i] = p.x, p.y
+	}
+
+	sort.Float64s(xs)
+	sort.Float64s(ys)
+
+	cellSize := mostCommonStep(append(coordinateSteps(xs), coordinateSteps(ys)...))
+	if cellSize == 0 {
+		return raster, fmt.Errorf("XYZ needs at least two distinct x or y coordinates")
+	}
+
+	minX, maxX := xs[0], xs[len(xs)-1]
+	minY, maxY := ys[0], ys[len(ys)-1]
+
+	raster.Ncols = uint(math.Round((maxX-minX)/cellSize)) + 1
+	raster.Nrows = uint(math.Round((maxY-minY)/cellSize)) + 1
+	raster.CellSize = cellSize
+	raster.NoDataValue = xyzNoDataValue
+	raster.HasNoData = true
+
+	// the grid points are the lower left corners of the cells, so that the
+	// first column is at minX and the last row at minY
+	xCorner := minX
+	yCorner := minY - cellSize
+	raster.Xcorner = &xCorner
+	raster.Ycorner = &yCorner
+
+	raster.Data = make([]float32, raster.Ncols*raster.Nrows)
+	for i := range raster.Data {
+		raster.Data[i] = xyzNoDataValue
+	}
+
+	for _, p := range points {
+		c, cOk := gridIndex(p.x-minX, cellSize)
+		r, rOk := gridIndex(p.y-minY, cellSize)
+		if !cOk || !rOk {
+			return raster, fmt.Errorf("Point %g %g isn't on the grid with a cell size of %g", p.x, p.y, cellSize)
+		}
+		raster.Set(c, raster.Nrows-1-r, p.z)
+	}
+
+	return raster, nil
+}
+
+// xyzGridTolerance is the part of the cell size, which a point may be off the grid
+const xyzGridTolerance = 0.01
+
+// coordinateSteps returns the differences between the distinct values of the sorted coordinates
+func coordinateSteps(sorted []float64) []float64 {
+	steps := []float64{}
+	for i := 1; i < len(sorted); i++ {
+		if d := sorted[i] - sorted[i-1]; d > 0 {
+			steps = append(steps, d)
+		}
+	}
+
+	return steps
+}
+
+// mostCommonStep returns the step which occurs most often or 0 if there are no steps.
+// Steps which only differ by rounding errors are counted as the same step.
+func mostCommonStep(steps []float64) float64 {
+	sort.Float64s(steps)
+
+	step := float64(0)
+	count := 0
+	for i := 0; i < len(steps); {
+		j := i + 1
+		for j < len(steps) && steps[j]-steps[i] <= steps[i]*xyzGridTolerance {
+			j++
+		}
+		if j-i > count {
+			step = steps[i]
+			count = j - i
+		}
+		i = j
+	}
+
+	return step
+}
+
+// gridIndex returns the index of the grid point at the offset and whether the offset is on the grid
+func gridIndex(offset float64, cellSize float64) (uint, bool) {
+	index := math.Round(offset / cellSize)
+
+	return uint(index), math.Abs(offset/cellSize-index) <= xyzGridTolerance
+}
+
+// xyzFormat is a list of "x y z" points
+type xyzFormat struct{}
+
+func (xyzFormat) Name() string {
+	return "XYZ point list"
+}
+
+func (xyzFormat) Match(filePath string, head []byte) bool {
+	return hasExtension(filePath, ".xyz", ".csv", ".txt")
+}
+
+func (xyzFormat) Decode(filePath string) (EsriASCIIRaster, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return EsriASCIIRaster{}, err
+	}
+	defer file.Close()
+
+	return ParseXYZ(bufio.NewReader(file))
+}
@@ -0,0 +1,95 @@
+package dem
+
+import (
+	"math"
+
+	"github.com/paulmach/orb"
+)
+
+// ProfilePoint is a sample of an elevation profile
+type ProfilePoint struct {
+	// Distance along the path from its start in meters
+	Distance float64 `json:"distance"`
+
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+
+	// Elevation is only valid if HasData is set
+	Elevation float64 `json:"elevation"`
+	HasData   bool    `json:"hasData"`
+}
+
+// Profile is the elevation along a path
+type Profile struct {
+	Points []ProfilePoint `json:"points"`
+
+	// Length of the path in meters
+	Length float64 `json:"length"`
+
+	// Ascent and Descent are the sums of all rises and falls between the samples
+	Ascent  float64 `json:"ascent"`
+	Descent float64 `json:"descent"`
+
+	MinElevation float64 `json:"minElevation"`
+	MaxElevation float64 `json:"maxElevation"`
+}
+
+// Profile samples the elevation along the path every interval meters and at each vertex of the path
+func (s Sampler) Profile(path orb.LineString, interval float64) Profile {
+	profile := Profile{
+		Points:       []ProfilePoint{},
+		MinElevation: math.Inf(1),
+		MaxElevation: math.Inf(-1),
+	}
+
+	add := func(distance, x, y float64) {
+		p := ProfilePoint{Distance: distance, X: x, Y: y}
+		p.Elevation, p.HasData = s.Elevation(x, y)
+		profile.Points = append(profile.Points, p)
+	}
+
+	for i := 1; i < len(path); i++ {
+		from, to := path[i-1], path[i]
+		length := math.Hypot(to[0]-from[0], to[1]-from[1])
+
+		// the end of a segment is the start of the next one
+		steps := int(math.Max(1, math.Ceil(length/interval)))
+		for step := 0; step < steps; step++ {
+			t := float64(step) / float64(steps)
+			add(profile.Length+t*length, from[0]+t*(to[0]-from[0]), from[1]+t*(to[1]-from[1]))
+		}
+
+		profile.Length += length
+	}
+	if len(path) > 0 {
+		last := path[len(path)-1]
+		add(profile.Length, last[0], last[1])
+	}
+
+	var previous *ProfilePoint
+	for i := range profile.Points {
+		p := &profile.Points[i]
+		if !p.HasData {
+			continue
+		}
+
+		profile.MinElevation = math.Min(profile.MinElevation, p.Elevation)
+		profile.MaxElevation = math.Max(profile.MaxElevation, p.Elevation)
+
+		if previous != nil {
+			if diff := p.Elevation - previous.Elevation; diff > 0 {
+				profile.Ascent += diff
+			} else {
+				profile.Descent -= diff
+			}
+		}
+		previous = p
+	}
+
+	// there are no elevations without data
+	if math.IsInf(profile.MinElevation, 1) {
+		profile.MinElevation, profile.MaxElevation = 0, 0
+	}
+
+	return profile
+}
@@ -6,32 +6,61 @@ import (
 	"os"
 )
 
-// Read digital elevation model from given path
+// Read digital elevation model from given path. The format is detected from the
+// extension or the content of the file (see Formats).
 func Read(path string) EsriASCIIRaster {
-	file, err := os.Open(path)
+	raster, err := Load(path)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	gz, err := gzip.NewReader(file)
-	if err != nil {
-		log.Fatal(err)
-	}
+	return raster
+}
 
-	raster, err := ParseEsriASCIIRaster(gz)
+// esriASCIIGzipFormat is a gzipped ESRI ASCII grid, like the dem.asc.gz of grad_meh
+type esriASCIIGzipFormat struct{}
+
+func (esriASCIIGzipFormat) Name() string {
+	return "gzipped ESRI ASCII grid"
+}
+
+func (esriASCIIGzipFormat) Match(filePath string, head []byte) bool {
+	return len(head) >= 2 && head[0] == 0x1f && head[1] == 0x8b
+}
+
+func (esriASCIIGzipFormat) Decode(filePath string) (EsriASCIIRaster, error) {
+	file, err := os.Open(filePath)
 	if err != nil {
-		log.Fatal(err)
+		return EsriASCIIRaster{}, err
 	}
+	defer file.Close()
 
-	err = file.Close()
+	gz, err := gzip.NewReader(file)
 	if err != nil {
-		log.Fatal(err)
+		return EsriASCIIRaster{}, err
 	}
+	defer gz.Close()
+
+	return ParseEsriASCIIRaster(gz)
+}
+
+// esriASCIIFormat is a plain ESRI ASCII grid
+type esriASCIIFormat struct{}
 
-	err = gz.Close()
+func (esriASCIIFormat) Name() string {
+	return "ESRI ASCII grid"
+}
+
+func (esriASCIIFormat) Match(filePath string, head []byte) bool {
+	return hasExtension(filePath, ".asc") || startsWithKeyword(head, "ncols") || startsWithKeyword(head, "nrows")
+}
+
+func (esriASCIIFormat) Decode(filePath string) (EsriASCIIRaster, error) {
+	file, err := os.Open(filePath)
 	if err != nil {
-		log.Fatal(err)
+		return EsriASCIIRaster{}, err
 	}
+	defer file.Close()
 
-	return raster
+	return ParseEsriASCIIRaster(file)
 }
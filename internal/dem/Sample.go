@@ -0,0 +1,158 @@
+package dem
+
+import (
+	"fmt"
+	"math"
+)
+
+// Interpolation defines how heights between the grid values are calculated
+type Interpolation string
+
+// supported interpolations
+const (
+	Nearest  Interpolation = "nearest"
+	Bilinear Interpolation = "bilinear"
+	Bicubic  Interpolation = "bicubic"
+)
+
+// Interpolations are all supported interpolations
+var Interpolations = []Interpolation{Nearest, Bilinear, Bicubic}
+
+// ParseInterpolation finds the interpolation with given name
+func ParseInterpolation(name string) (Interpolation, error) {
+	for _, i := range Interpolations {
+		if string(i) == name {
+			return i, nil
+		}
+	}
+
+	return "", fmt.Errorf("Unknown interpolation: %s", name)
+}
+
+// Col returns the (fractional) column index of the x coordinate. It is the inverse of X.
+func (raster EsriASCIIRaster) Col(x float64) float64 {
+	return (x - raster.X(0)) / raster.CellSize
+}
+
+// Row returns the (fractional) row index of the y coordinate. It is the inverse of Y.
+func (raster EsriASCIIRaster) Row(y float64) float64 {
+	return (raster.Y(0) - y) / raster.CellSize
+}
+
+// Sample returns the height of the grid at the world coordinate (x, y). The grid value at
+// (c, r) is the height at (X(c), Y(r)) and heights in between are interpolated. Grid values
+// without data are left out. ok is false if the coordinate is outside of the grid or
+// there is no data around it.
+func (raster EsriASCIIRaster) Sample(x, y float64, interpolation Interpolation) (z float64, ok bool) {
+	col, row := raster.Col(x), raster.Row(y)
+
+	if col < 0 || row < 0 || col > float64(raster.Ncols-1) || row > float64(raster.Nrows-1) {
+		return 0, false
+	}
+
+	switch interpolation {
+	case Nearest:
+		c, r := uint(math.Round(col)), uint(math.Round(row))
+		if raster.IsNoData(c, r) {
+			return 0, false
+		}
+		return raster.Z(c, r), true
+	case Bicubic:
+		if z, ok := raster.bicubic(col, row); ok {
+			return z, true
+		}
+	}
+
+	return raster.bilinear(col, row)
+}
+
+// bilinear interpolates between the four grid values around (col, row)
+func (raster EsriASCIIRaster) bilinear(col, row float64) (float64, bool) {
+	c0, r0 := uint(col), uint(row)
+	c1, r1 := minUint(c0+1, raster.Ncols-1), minUint(r0+1, raster.Nrows-1)
+	fx, fy := col-float64(c0), row-float64(r0)
+
+	var sum, weights float64
+	add := func(c, r uint, weight float64) {
+		if weight == 0 || raster.IsNoData(c, r) {
+			return
+		}
+		sum += weight * raster.Z(c, r)
+		weights += weight
+	}
+
+	add(c0, r0, (1-fx)*(1-fy))
+	add(c1, r0, fx*(1-fy))
+	add(c0, r1, (1-fx)*fy)
+	add(c1, r1, fx*fy)
+
+	if weights == 0 {
+		return 0, false
+	}
+
+	return sum / weights, true
+}
+
+// bicubic interpolates with a Catmull-Rom spline through the 4x4 grid values around (col, row).
+// At the edges of the grid the outermost values are repeated. It fails if any of the values has no data.
+func (raster EsriASCIIRaster) bicubic(col, row float64) (float64, bool) {
+	c0, r0 := int(col), int(row)
+	fx, fy := col-float64(c0), row-float64(r0)
+
+	clampIndex := func(i int, n uint) uint {
+		if i < 0 {
+			return 0
+		}
+		if i >= int(n) {
+			return n - 1
+		}
+		return uint(i)
+	}
+
+	var rows [4]float64
+	for i := 0; i < 4; i++ {
+		r := clampIndex(r0-1+i, raster.Nrows)
+
+		var values [4]float64
+		for j := 0; j < 4; j++ {
+			c := clampIndex(c0-1+j, raster.Ncols)
+			if raster.IsNoData(c, r) {
+				return 0, false
+			}
+			values[j] = raster.Z(c, r)
+		}
+
+		rows[i] = catmullRom(values, fx)
+	}
+
+	return catmullRom(rows, fy), true
+}
+
+// catmullRom interpolates between p[1] and p[2] at t (0 - 1)
+func catmullRom(p [4]float64, t float64) float64 {
+	return p[1] + 0.5*t*(p[2]-p[0]+t*(2*p[0]-5*p[1]+4*p[2]-p[3]+t*(3*(p[1]-p[2])+p[3]-p[0])))
+}
+
+func minUint(a uint, b uint) uint {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Sampler returns elevations at world coordinates, which include the elevation offset of the world
+type Sampler struct {
+	Raster          EsriASCIIRaster
+	ElevationOffset float64
+	Interpolation   Interpolation
+}
+
+// Elevation returns the elevation at the world coordinate (x, y). ok is false if there is no data at the coordinate.
+func (s Sampler) Elevation(x, y float64) (elevation float64, ok bool) {
+	z, ok := s.Raster.Sample(x, y, s.Interpolation)
+	if !ok {
+		return 0, false
+	}
+
+	return z + s.ElevationOffset, true
+}
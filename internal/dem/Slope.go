@@ -0,0 +1,28 @@
+package dem
+
+import "math"
+
+// Slope returns the steepness of the terrain at the cell (c, r) in degrees (0 - 90)
+func (raster EsriASCIIRaster) Slope(c, r uint) float64 {
+	dzdx, dzdy := raster.Gradient(c, r)
+
+	return math.Atan(math.Hypot(dzdx, dzdy)) * 180 / math.Pi
+}
+
+// Aspect returns the direction the terrain at the cell (c, r) faces in degrees clockwise
+// from north (0 - 360). Flat cells don't face any direction and return -1.
+func (raster EsriASCIIRaster) Aspect(c, r uint) float64 {
+	dzdx, dzdy := raster.Gradient(c, r)
+
+	if dzdx == 0 && dzdy == 0 {
+		return -1
+	}
+
+	// the terrain faces downhill, which is the opposite of the gradient
+	aspect := math.Atan2(-dzdx, -dzdy) * 180 / math.Pi
+	if aspect < 0 {
+		aspect += 360
+	}
+
+	return aspect
+}
@@ -0,0 +1,127 @@
+package dem
+
+import (
+	"math"
+)
+
+// LineOfSight checks whether a target at (toX, toY) can be seen by an observer at (fromX, fromY).
+// The eye of the observer is eyeHeight above the ground and the target is targetHeight above the
+// ground. The terrain between them is sampled every half cell. If the target can't be seen, the
+// position where the terrain blocks the line of sight is returned. ok is false if there is no data
+// at the observer or the target.
+func (raster EsriASCIIRaster) LineOfSight(fromX, fromY, eyeHeight, toX, toY, targetHeight float64) (visible bool, blockedX, blockedY float64, ok bool) {
+	fromZ, ok := raster.Sample(fromX, fromY, Bilinear)
+	if !ok {
+		return false, 0, 0, false
+	}
+	toZ, ok := raster.Sample(toX, toY, Bilinear)
+	if !ok {
+		return false, 0, 0, false
+	}
+
+	eyeZ := fromZ + eyeHeight
+	targetZ := toZ + targetHeight
+
+	steps := int(math.Ceil(math.Hypot(toX-fromX, toY-fromY) / (raster.CellSize / 2)))
+	for i := 1; i < steps; i++ {
+		t := float64(i) / float64(steps)
+		x, y := fromX+t*(toX-fromX), fromY+t*(toY-fromY)
+
+		z, found := raster.Sample(x, y, Bilinear)
+		if found && z > eyeZ+t*(targetZ-eyeZ) {
+			return false, x, y, true
+		}
+	}
+
+	return true, 0, 0, true
+}
+
+// Viewshed calculates which cells can be seen by an observer at (x, y), whose eye is eyeHeight
+// above the ground. A cell is visible if a target targetHeight above it can be seen and it is at
+// most maxRange away. Only the cells within the square around the range can be visible, so the
+// result only covers this window of the grid. Cells without data are never visible.
+//
+// Rays are cast from the observer to every cell on the border of the square around the range.
+// Along each ray the steepest angle to the terrain so far is tracked and every cell which rises
+// above it is visible (the R2 algorithm by Franklin and Ray).
+func (raster EsriASCIIRaster) Viewshed(x, y, eyeHeight, targetHeight, maxRange float64) (visibility Visibility, ok bool) {
+	groundZ, ok := raster.Sample(x, y, Bilinear)
+	if !ok {
+		return visibility, false
+	}
+
+	eyeZ := groundZ + eyeHeight
+	col, row := raster.Col(x), raster.Row(y)
+	radius := int(math.Ceil(maxRange / raster.CellSize))
+
+	// window of all cells, which can be reached by a ray
+	firstCol := uint(math.Max(math.Floor(col)-float64(radius), 0))
+	firstRow := uint(math.Max(math.Floor(row)-float64(radius), 0))
+	lastCol := uint(math.Min(math.Ceil(col)+float64(radius), float64(raster.Ncols-1)))
+	lastRow := uint(math.Min(math.Ceil(row)+float64(radius), float64(raster.Nrows-1)))
+	visibility = Visibility{
+		Col:     firstCol,
+		Row:     firstRow,
+		Ncols:   lastCol - firstCol + 1,
+		Nrows:   lastRow - firstRow + 1,
+		Visible: make([]bool, (lastCol-firstCol+1)*(lastRow-firstRow+1)),
+	}
+	markVisible := func(c, r uint) {
+		visibility.Visible[(r-firstRow)*visibility.Ncols+c-firstCol] = true
+	}
+
+	// the cell of the observer is always visible
+	if c, r := uint(math.Round(col)), uint(math.Round(row)); !raster.IsNoData(c, r) {
+		markVisible(c, r)
+	}
+
+	castRay := func(dc, dr float64) {
+		steps := int(math.Max(math.Abs(dc), math.Abs(dr)))
+		maxSlope := math.Inf(-1)
+
+		for i := 1; i <= steps; i++ {
+			t := float64(i) / float64(steps)
+			fc, fr := col+t*dc, row+t*dr
+
+			if fc < 0 || fr < 0 || fc > float64(raster.Ncols-1) || fr > float64(raster.Nrows-1) {
+				return
+			}
+
+			distance := t * math.Hypot(dc, dr) * raster.CellSize
+			if distance > maxRange {
+				return
+			}
+
+			z, found := raster.bilinear(fc, fr)
+			if !found {
+				continue
+			}
+
+			c, r := uint(math.Round(fc)), uint(math.Round(fr))
+			if (z+targetHeight-eyeZ)/distance >= maxSlope && !raster.IsNoData(c, r) {
+				markVisible(c, r)
+			}
+
+			maxSlope = math.Max(maxSlope, (z-eyeZ)/distance)
+		}
+	}
+
+	for i := -radius; i <= radius; i++ {
+		castRay(float64(i), float64(-radius))
+		castRay(float64(i), float64(radius))
+		castRay(float64(-radius), float64(i))
+		castRay(float64(radius), float64(i))
+	}
+
+	return visibility, true
+}
+
+// Visibility is the result of a viewshed. It covers a window of the grid, which starts at the
+// cell (Col, Row) and is Ncols cells wide and Nrows cells high.
+type Visibility struct {
+	Col, Row     uint
+	Ncols, Nrows uint
+
+	// Visible has a value for each cell of the window (row by row)
+	Visible []bool
+}
@@ -0,0 +1,198 @@
+package dem
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/paulmach/orb"
+)
+
+// number of cell rows, which are traced at once by a worker
+const marchingSquaresStripRows = 64
+
+// MarchingSquaresLevels calculates the contour lines of all given levels in a single pass over the
+// raster. The lines of each level are returned at the same index as the level.
+//
+// The raster is split into strips of rows, which are traced concurrently. Each cell is classified
+// once and only the levels between its lowest and highest corner are traced, so a worker only
+// holds the segments of a single strip. Afterwards the lines of all strips are joined. Like
+// MarchingSquaresPolygons, the lines are directed so that the higher ground is on their left.
+func MarchingSquaresLevels(raster *EsriASCIIRaster, levels []float64) [][]orb.LineString {
+	result := make([][]orb.LineString, len(levels))
+	for i := range result {
+		result[i] = []orb.LineString{}
+	}
+	if raster.Ncols < 2 || raster.Nrows < 2 || len(levels) == 0 {
+		return result
+	}
+
+	// levels in ascending order and their index in levels
+	order := make([]int, len(levels))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return levels[order[i]] < levels[order[j]] })
+	sorted := make([]float64, len(levels))
+	for i, index := range order {
+		sorted[i] = levels[index]
+	}
+
+	// strip -> sorted level -> lines
+	numOfStrips := int((raster.Nrows - 1 + marchingSquaresStripRows - 1) / marchingSquaresStripRows)
+	strips := make([][][]orb.LineString, numOfStrips)
+
+	jobs := make(chan int)
+	waitGrp := sync.WaitGroup{}
+	for w := 0; w < runtime.NumCPU(); w++ {
+		waitGrp.Add(1)
+		go func() {
+			defer waitGrp.Done()
+
+			for strip := range jobs {
+				fromRow := uint(strip) * marchingSquaresStripRows
+				toRow := fromRow + marchingSquaresStripRows
+				if toRow > raster.Nrows-1 {
+					toRow = raster.Nrows - 1
+				}
+
+				strips[strip] = traceStrip(raster, sorted, fromRow, toRow)
+			}
+		}()
+	}
+	for strip := range strips {
+		jobs <- strip
+	}
+	close(jobs)
+	waitGrp.Wait()
+
+	// join the lines, which cross the borders of the strips
+	levelJobs := make(chan int)
+	for w := 0; w < runtime.NumCPU(); w++ {
+		waitGrp.Add(1)
+		go func() {
+			defer waitGrp.Done()
+
+			for level := range levelJobs {
+				lines := []orb.LineString{}
+				for _, strip := range strips {
+					lines = append(lines, strip[level]...)
+				}
+
+				result[order[level]] = chainLines(lines)
+			}
+		}()
+	}
+	for level := range sorted {
+		levelJobs <- level
+	}
+	close(levelJobs)
+	waitGrp.Wait()
+
+	return result
+}
+
+// traceStrip calculates the lines of all (sorted) levels within the cell rows from fromRow to toRow
+// (exclusive). Lines which cross the border of the strip end there.
+func traceStrip(raster *EsriASCIIRaster, levels []float64, fromRow, toRow uint) [][]orb.LineString {
+	segments := make([][]msSegment, len(levels))
+
+	for row := fromRow; row < toRow; row++ {
+		for col := uint(0); col < raster.Ncols-1; col++ {
+			if !isValidCell(raster, int(col), int(row)) {
+				continue
+			}
+
+			// corners in counter-clockwise order (bottom left, bottom right, top right, top left)
+			corners := [4]cell_{{col, row + 1}, {col + 1, row + 1}, {col + 1, row}, {col, row}}
+
+			min, max := math.Inf(1), math.Inf(-1)
+			for _, c := range corners {
+				z := raster.Z(c.Col, c.Row)
+				min = math.Min(min, z)
+				max = math.Max(max, z)
+			}
+
+			// a level is crossed, if a corner is at or below it and another one is above it
+			for i := sort.SearchFloat64s(levels, min); i < len(levels) && levels[i] < max; i++ {
+				segments[i] = appendIsoSegments(segments[i], raster, corners, levels[i])
+			}
+		}
+	}
+
+	lines := make([][]orb.LineString, len(levels))
+	for i, levelSegments := range segments {
+		// all segments share one array of points
+		points := make([]orb.Point, 2*len(levelSegments))
+		segmentLines := make([]orb.LineString, len(levelSegments))
+		for j, s := range levelSegments {
+			points[2*j] = s.From
+			points[2*j+1] = s.To
+			segmentLines[j] = points[2*j : 2*j+2 : 2*j+2]
+		}
+
+		lines[i] = chainLines(segmentLines)
+	}
+
+	return lines
+}
+
+// chainLines joins all lines, which end where another line starts. Open lines are followed from
+// their start, so they aren't split, if they are part of a longer line.
+func chainLines(lines []orb.LineString) []orb.LineString {
+	starts := make(map[orb.Point][]int, len(lines))
+	for i, line := range lines {
+		starts[line[0]] = append(starts[line[0]], i)
+	}
+
+	hasPredecessor := make([]bool, len(lines))
+	for i, line := range lines {
+		for _, j := range starts[line[len(line)-1]] {
+			if j != i {
+				hasPredecessor[j] = true
+			}
+		}
+	}
+
+	used := make([]bool, len(lines))
+	chained := []orb.LineString{}
+
+	follow := func(first int) {
+		used[first] = true
+		line := append(orb.LineString{}, lines[first]...)
+
+		// follow the line until it is closed or there is no next line
+		for line[0] != line[len(line)-1] {
+			next := -1
+			for _, j := range starts[line[len(line)-1]] {
+				if !used[j] {
+					next = j
+					break
+				}
+			}
+			if next < 0 {
+				break
+			}
+
+			used[next] = true
+			line = append(line, lines[next][1:]...)
+		}
+
+		chained = append(chained, line)
+	}
+
+	// lines without a predecessor first, the remaining lines are rings
+	for i := range lines {
+		if !used[i] && !hasPredecessor[i] {
+			follow(i)
+		}
+	}
+	for i := range lines {
+		if !used[i] {
+			follow(i)
+		}
+	}
+
+	return chained
+}
@@ -0,0 +1,305 @@
+package dem
+
+import (
+	"math"
+	"sort"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+)
+
+// MarchingSquaresPolygons calculates the polygons of the area, in which the raster is above min
+// and at most max (min < z <= max). Use math.Inf for bands which are open to one side.
+//
+// Saddle cells are resolved with the average of their corners, so the polygons match the lines of
+// MarchingSquaresLevels. Rings are closed along the edge of the raster and around cells with a corner
+// without data. Outer rings are counter-clockwise and holes clockwise.
+func MarchingSquaresPolygons(raster *EsriASCIIRaster, min, max float64) []orb.Polygon {
+	if raster.Ncols < 2 || raster.Nrows < 2 {
+		return []orb.Polygon{}
+	}
+
+	segments := bandSegments(raster, min, max)
+	rings := chainSegments(segments)
+
+	return nestRings(rings)
+}
+
+// cell_ is a grid value of the raster. The cells of marching squares are between four of them.
+type cell_ struct {
+	Col uint
+	Row uint
+}
+
+// msSegment is a part of the outline of an area. It is directed so that the area is on its left.
+type msSegment struct {
+	From orb.Point
+	To   orb.Point
+}
+
+// bandSegments calculates the outline of the band of every cell
+func bandSegments(raster *EsriASCIIRaster, min, max float64) []msSegment {
+	segments := []msSegment{}
+
+	add := func(from, to orb.Point) {
+		if from != to {
+			segments = append(segments, msSegment{from, to})
+		}
+	}
+
+	for row := uint(0); row < raster.Nrows-1; row++ {
+		for col := uint(0); col < raster.Ncols-1; col++ {
+			if !isValidCell(raster, int(col), int(row)) {
+				continue
+			}
+
+			// corners in counter-clockwise order (bottom left, bottom right, top right, top left)
+			corners := [4]cell_{{col, row + 1}, {col + 1, row + 1}, {col + 1, row}, {col, row}}
+
+			// the band is above min
+			if !math.IsInf(min, -1) {
+				segments = appendIsoSegments(segments, raster, corners, min)
+			}
+
+			// ...and not above max, so the area above max has to be on the right
+			if !math.IsInf(max, 1) {
+				n := len(segments)
+				segments = appendIsoSegments(segments, raster, corners, max)
+				for i := n; i < len(segments); i++ {
+					segments[i].From, segments[i].To = segments[i].To, segments[i].From
+				}
+			}
+
+			// edges of the cell, which are on the edge of the raster or next to cells without
+			// data, are part of the outline as far as they are within the band
+			neighbours := [4][2]int{{int(col), int(row) + 1}, {int(col) + 1, int(row)}, {int(col), int(row) - 1}, {int(col) - 1, int(row)}}
+			for i, n := range neighbours {
+				if isValidCell(raster, n[0], n[1]) {
+					continue
+				}
+
+				from, to, ok := bandPortion(raster, corners[i], corners[(i+1)%4], min, max)
+				if ok {
+					add(from, to)
+				}
+			}
+		}
+	}
+
+	return segments
+}
+
+// isValidCell checks whether the cell is within the raster and all of its corners have data
+func isValidCell(raster *EsriASCIIRaster, col, row int) bool {
+	if col < 0 || row < 0 || col >= int(raster.Ncols)-1 || row >= int(raster.Nrows)-1 {
+		return false
+	}
+
+	c, r := uint(col), uint(row)
+	return !raster.IsNoData(c, r) && !raster.IsNoData(c+1, r) && !raster.IsNoData(c+1, r+1) && !raster.IsNoData(c, r+1)
+}
+
+// appendIsoSegments appends the contour line bits of a cell for the given level to segments. They
+// are directed so that the area above level is on their left. The corners have to be in
+// counter-clockwise order.
+func appendIsoSegments(segments []msSegment, raster *EsriASCIIRaster, corners [4]cell_, level float64) []msSegment {
+	type crossing struct {
+		Point orb.Point
+		Leave bool // whether the outline of the cell leaves the area above level here
+	}
+
+	var crossings [4]crossing
+	n := 0
+	sum := float64(0)
+	for i := 0; i < 4; i++ {
+		a := corners[i]
+		b := corners[(i+1)%4]
+		aboveA := raster.Z(a.Col, a.Row) > level
+		aboveB := raster.Z(b.Col, b.Row) > level
+		sum += raster.Z(a.Col, a.Row)
+
+		if aboveA != aboveB {
+			crossings[n] = crossing{edgePoint(raster, a, b, level), aboveA}
+			n++
+		}
+	}
+
+	add := func(from, to orb.Point) {
+		if from != to {
+			segments = append(segments, msSegment{from, to})
+		}
+	}
+
+	switch n {
+	case 2:
+		if crossings[0].Leave {
+			add(crossings[0].Point, crossings[1].Point)
+		} else {
+			add(crossings[1].Point, crossings[0].Point)
+		}
+	case 4:
+		// saddle: if the center of the cell is above level, the corners above level are connected,
+		// so each line goes to the next crossing. Otherwise they are separated, so each line goes
+		// back to the crossing, at which the outline of the cell entered the area.
+		connected := sum/4 > level
+
+		for i, c := range crossings {
+			if !c.Leave {
+				continue
+			}
+
+			if connected {
+				add(c.Point, crossings[(i+1)%4].Point)
+			} else {
+				add(c.Point, crossings[(i+3)%4].Point)
+			}
+		}
+	}
+
+	return segments
+}
+
+// bandPortion returns the part of the edge from a to b, which is within the band
+func bandPortion(raster *EsriASCIIRaster, a, b cell_, min, max float64) (orb.Point, orb.Point, bool) {
+	za := raster.Z(a.Col, a.Row)
+	zb := raster.Z(b.Col, b.Row)
+	inBand := func(z float64) bool { return z > min && z <= max }
+
+	var from, to orb.Point
+	switch {
+	case inBand(za):
+		from = orb.Point{raster.X(a.Col), raster.Y(a.Row)}
+	case za <= min && zb > min:
+		from = edgePoint(raster, a, b, min)
+	case za > max && zb <= max:
+		from = edgePoint(raster, a, b, max)
+	default:
+		return from, to, false
+	}
+
+	switch {
+	case inBand(zb):
+		to = orb.Point{raster.X(b.Col), raster.Y(b.Row)}
+	case zb <= min && za > min:
+		to = edgePoint(raster, a, b, min)
+	case zb > max && za <= max:
+		to = edgePoint(raster, a, b, max)
+	default:
+		return from, to, false
+	}
+
+	return from, to, true
+}
+
+// edgePoint interpolates the point on the edge between two neighbouring grid values, at which the
+// raster is at level. Both cells next to the edge get exactly the same point, because it always
+// interpolates from bottom to top and from left to right.
+func edgePoint(raster *EsriASCIIRaster, a, b cell_, level float64) orb.Point {
+	if b.Row > a.Row || b.Col < a.Col {
+		a, b = b, a
+	}
+
+	return orb.Point{
+		interpolate(raster.X(a.Col), raster.Z(a.Col, a.Row), raster.X(b.Col), raster.Z(b.Col, b.Row), level),
+		interpolate(raster.Y(a.Row), raster.Z(a.Col, a.Row), raster.Y(b.Row), raster.Z(b.Col, b.Row), level),
+	}
+}
+
+// chainSegments connects the segments to closed rings
+func chainSegments(segments []msSegment) []orb.Ring {
+	outgoing := make(map[orb.Point][]int, len(segments))
+	for i, s := range segments {
+		outgoing[s.From] = append(outgoing[s.From], i)
+	}
+
+	used := make([]bool, len(segments))
+	rings := []orb.Ring{}
+
+	for start := range segments {
+		if used[start] {
+			continue
+		}
+
+		ring := orb.Ring{segments[start].From}
+		current := start
+		closed := false
+		for {
+			used[current] = true
+			to := segments[current].To
+			ring = append(ring, to)
+
+			if to == segments[start].From {
+				closed = true
+				break
+			}
+
+			// find next unused segment
+			next := -1
+			for _, i := range outgoing[to] {
+				if !used[i] {
+					next = i
+					break
+				}
+			}
+			if next < 0 {
+				break
+			}
+			current = next
+		}
+
+		// every segment has a successor, so rings only stay open because of floating point errors
+		if closed && len(ring) >= 4 {
+			rings = append(rings, ring)
+		}
+	}
+
+	return rings
+}
+
+// nestRings builds polygons from counter-clockwise outer rings and clockwise holes. Each hole
+// belongs to the smallest outer ring, which contains it.
+func nestRings(rings []orb.Ring) []orb.Polygon {
+	type outer struct {
+		Ring  orb.Ring
+		Bound orb.Bound
+		Area  float64
+	}
+
+	outers := []outer{}
+	holes := []orb.Ring{}
+	for _, ring := range rings {
+		area := planar.Area(ring)
+		switch ring.Orientation() {
+		case orb.CCW:
+			outers = append(outers, outer{ring, ring.Bound(), area})
+		case orb.CW:
+			holes = append(holes, ring)
+		}
+	}
+
+	sort.Slice(outers, func(i, j int) bool { return outers[i].Area < outers[j].Area })
+
+	polygons := make([]orb.Polygon, len(outers))
+	for i, o := range outers {
+		polygons[i] = orb.Polygon{o.Ring}
+	}
+
+	for _, hole := range holes {
+		bound := hole.Bound()
+		point := orb.Point{(hole[0][0] + hole[1][0]) / 2, (hole[0][1] + hole[1][1]) / 2}
+
+		for i, o := range outers {
+			if o.Bound.Contains(bound.Min) && o.Bound.Contains(bound.Max) && planar.RingContains(o.Ring, point) {
+				polygons[i] = append(polygons[i], hole)
+				break
+			}
+		}
+	}
+
+	return polygons
+}
+
+// linear interpolations between two known points
+func interpolate(c0, h0, c1, h1, height float64) float64 {
+	return (c0*(h1-height) + c1*(height-h0)) / (h1 - h0)
+}
@@ -0,0 +1,127 @@
+package demtiles
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gruppe-adler/meh-utils/internal/colorramp"
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+	"github.com/gruppe-adler/meh-utils/internal/metajson"
+	"github.com/gruppe-adler/meh-utils/internal/tilejson"
+	"github.com/gruppe-adler/meh-utils/internal/tilewriter"
+	"github.com/gruppe-adler/meh-utils/internal/utils"
+	"github.com/gruppe-adler/meh-utils/internal/validate"
+)
+
+// Flags are the flags of the subcommands, which color every DEM cell with a color ramp
+type Flags struct {
+	output      *string
+	input       *string
+	format      *string
+	ramp        *string
+	interpolate *bool
+}
+
+// AddFlags defines the shared flags on given flag set. rampUsage describes the values of the color ramp.
+func AddFlags(flagSet *flag.FlagSet, rampUsage string) Flags {
+	return Flags{
+		output:      flagSet.String("out", "", "Path to output directory (or output file for -format mbtiles / pmtiles)"),
+		input:       flagSet.String("in", "", "Path to grad_meh map directory"),
+		format:      flagSet.String("format", tilewriter.Directory, fmt.Sprintf("Output format (%s)", strings.Join(tilewriter.Formats, ", "))),
+		ramp:        flagSet.String("ramp", "", fmt.Sprintf("Path to color ramp for %s (gdaldem color-relief format, built-in classes if empty)", rampUsage)),
+		interpolate: flagSet.Bool("interpolate", false, "Interpolate between the stops of -ramp instead of using them as classes"),
+	}
+}
+
+// Parse parses the command line and exits if the input or output is missing
+func (flags Flags) Parse(flagSet *flag.FlagSet) {
+	flagSet.Parse(os.Args[2:])
+
+	// make sure both flags are present
+	if *flags.output == "" || *flags.input == "" {
+		flagSet.PrintDefaults()
+		os.Exit(1)
+	}
+}
+
+// Build loads the DEM, colors it with calculateImage and writes the tiles of the image.
+// The color ramp of the -ramp flag replaces defaultRamp. name is the lower case name of
+// the tileset used in the progress messages.
+func Build(flags Flags, name string, defaultRamp colorramp.Ramp, calculateImage func(raster dem.EsriASCIIRaster, ramp colorramp.Ramp) *image.RGBA) {
+
+	var timer time.Time
+	start := time.Now()
+
+	// create tile writer (makes sure the output path is valid)
+	writer, err := tilewriter.New(*flags.format, *flags.output, "png")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// load color ramp
+	ramp := defaultRamp
+	if *flags.ramp != "" {
+		ramp, err = colorramp.Read(*flags.ramp)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ramp.Discrete = !*flags.interpolate
+	}
+
+	// validate input directory structure
+	err = validate.MehDirectory(*flags.input)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("✔️  Validated input directory structure")
+
+	// load meta.json
+	timer = time.Now()
+	fmt.Println("▶️  Loading meta.json")
+	meta, err := metajson.Read(path.Join(*flags.input, "meta.json"))
+	if err != nil {
+		log.Fatal(errors.New("Failed to read meta.json"))
+	}
+	fmt.Println("✔️  Loaded meta.json in", time.Now().Sub(timer).String())
+
+	// load DEM
+	timer = time.Now()
+	fmt.Println("▶️  Loading DEM")
+	raster := dem.Read(path.Join(*flags.input, "dem.asc.gz"))
+	fmt.Println("✔️  Loaded DEM in", time.Now().Sub(timer).String())
+
+	// calculating image
+	timer = time.Now()
+	fmt.Printf("▶️  Calculating %s from DEM\n", name)
+	img := calculateImage(raster, ramp)
+	fmt.Printf("✔️  Calculated %s in %s\n", name, time.Now().Sub(timer).String())
+
+	// calculate max LOD
+	maxLod := utils.CalcMaxLodFromImage(img)
+	fmt.Println("ℹ️  Calculated max lod:", maxLod)
+
+	// build tiles (the pixels are classes, so they must not be blended)
+	timer = time.Now()
+	fmt.Println("▶️  Building tiles")
+	utils.BuildTilePyramid(maxLod, img, utils.NearestResampler{}, writer)
+	fmt.Printf("✔️  Built %s tiles in %s\n", name, time.Now().Sub(timer).String())
+
+	// write tile.json / metadata
+	timer = time.Now()
+	fmt.Println("▶️  Writing tileset metadata")
+	err = writer.Close(tilejson.New(maxLod, meta, strings.Title(name), []string{}))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("✔️  Wrote tileset metadata in", time.Now().Sub(timer).String())
+
+	fmt.Printf("\n    🎉  Finished in %s\n", time.Now().Sub(start).String())
+}
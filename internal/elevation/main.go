@@ -0,0 +1,154 @@
+package elevation
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+	"github.com/gruppe-adler/meh-utils/internal/metajson"
+	"github.com/gruppe-adler/meh-utils/internal/utils"
+	"github.com/gruppe-adler/meh-utils/internal/validate"
+	"github.com/paulmach/orb"
+)
+
+// Run is the program's entrypoint. Unlike the other subcommands it doesn't print any
+// progress, so that its output can be piped into other tools.
+func Run(flagSet *flag.FlagSet) {
+
+	inputPtr := flagSet.String("in", "", "Path to grad_meh map directory")
+	demPtr := flagSet.String("dem", "", "Path to DEM (ESRI ASCII grid, XYZ, GeoTIFF or ESRI float grid), defaults to the dem.asc.gz of the grad_meh map directory")
+	csvPtr := flagSet.String("csv", "", "Path to CSV file with x and y in the first two columns (\"-\" for stdin). An elevation column is appended to each row.")
+	interpolationPtr := flagSet.String("interpolation", string(dem.Bilinear), fmt.Sprintf("Interpolation between DEM cells (%s, %s, %s)", dem.Nearest, dem.Bilinear, dem.Bicubic))
+
+	flagSet.Usage = func() {
+		fmt.Fprintf(flagSet.Output(), "Usage: %s elevation -in DIR [-csv FILE] [X,Y ...]\n\n", os.Args[0])
+		fmt.Fprintf(flagSet.Output(), "Prints x,y,elevation for each coordinate. Coordinates are read from the arguments,\nthe -csv file or line by line from stdin.\n\n")
+		flagSet.PrintDefaults()
+	}
+
+	flagSet.Parse(os.Args[2:])
+
+	if *inputPtr == "" {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	interpolation, err := dem.ParseInterpolation(*interpolationPtr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	demPath := *demPtr
+	if demPath == "" {
+		demPath = path.Join(*inputPtr, "dem.asc.gz")
+	}
+
+	// validate input directory structure
+	err = validate.MehDirectoryWithDEM(*inputPtr, demPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	meta, err := metajson.Read(path.Join(*inputPtr, "meta.json"))
+	if err != nil {
+		log.Fatal(errors.New("Failed to read meta.json"))
+	}
+
+	sampler := dem.Sampler{
+		Raster:          dem.Read(demPath),
+		ElevationOffset: meta.ElevationOffset,
+		Interpolation:   interpolation,
+	}
+
+	out := csv.NewWriter(os.Stdout)
+
+	switch {
+	case *csvPtr != "":
+		err = sampleCSV(sampler, *csvPtr, out)
+	case flagSet.NArg() > 0:
+		err = sampleLines(sampler, strings.NewReader(strings.Join(flagSet.Args(), "\n")), out)
+	default:
+		err = sampleLines(sampler, os.Stdin, out)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out.Flush()
+	if err = out.Error(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// sampleLines reads one coordinate per line ("x,y" or "x y") and writes x, y and the elevation.
+// Empty lines and lines starting with # are skipped.
+func sampleLines(sampler dem.Sampler, reader io.Reader, out *csv.Writer) error {
+	return utils.ScanCoordinates(reader, func(p orb.Point) error {
+		x := strconv.FormatFloat(p[0], 'f', -1, 64)
+		y := strconv.FormatFloat(p[1], 'f', -1, 64)
+
+		return out.Write([]string{x, y, formatElevation(sampler, p[0], p[1])})
+	})
+}
+
+// sampleCSV appends the elevation to each row of the CSV file. A first row,
+// whose first two columns aren't numbers, is treated as header.
+func sampleCSV(sampler dem.Sampler, csvPath string, out *csv.Writer) error {
+	var reader io.Reader = os.Stdin
+	if csvPath != "-" {
+		file, err := os.Open(csvPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	in := csv.NewReader(reader)
+	in.FieldsPerRecord = -1
+
+	for rowNumber := 1; ; rowNumber++ {
+		record, err := in.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		p, err := utils.ParseCoordinateFields(record)
+		if err != nil {
+			if rowNumber == 1 {
+				err = out.Write(append(record, "elevation"))
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			return fmt.Errorf("Row %d: %s", rowNumber, err)
+		}
+
+		err = out.Write(append(record, formatElevation(sampler, p[0], p[1])))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// formatElevation returns the elevation with centimeter precision or an empty string, if there is no data at the coordinate
+func formatElevation(sampler dem.Sampler, x, y float64) string {
+	elevation, ok := sampler.Elevation(x, y)
+	if !ok {
+		return ""
+	}
+
+	return strconv.FormatFloat(elevation, 'f', 2, 64)
+}
@@ -0,0 +1,273 @@
+package geotiff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"image"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// tileSize is the width and height of the internal tiles in pixels
+const tileSize = 512
+
+// rasters with more uncompressed data than this are written as BigTIFF
+const bigTIFFThreshold = 1 << 31
+
+// TIFF tags and values
+const (
+	tagNewSubfileType            = 254
+	tagImageWidth                = 256
+	tagImageLength               = 257
+	tagBitsPerSample             = 258
+	tagCompression               = 259
+	tagPhotometricInterpretation = 262
+	tagSamplesPerPixel           = 277
+	tagPlanarConfiguration       = 284
+	tagTileWidth                 = 322
+	tagTileLength                = 323
+	tagTileOffsets               = 324
+	tagTileByteCounts            = 325
+	tagExtraSamples              = 338
+	tagSampleFormat              = 339
+	tagModelPixelScale           = 33550
+	tagModelTiepoint             = 33922
+	tagGeoKeyDirectory           = 34735
+	tagGeoASCIIParams            = 34737
+	tagGDALNoData                = 42113
+
+	subfileTypeReducedImage    = 1
+	compressionDeflate         = 8
+	photometricMinIsBlack      = 1
+	photometricRGB             = 2
+	planarConfigurationChunky  = 1
+	extraSampleAssociatedAlpha = 1
+	sampleFormatUint           = 1
+	sampleFormatFloat          = 3
+)
+
+// GeoTIFF keys and values
+const (
+	keyGTModelType     = 1024
+	keyGTRasterType    = 1025
+	keyGTCitation      = 1026
+	keyProjectedCSType = 3072
+	keyProjLinearUnits = 3076
+	modelTypeProjected = 1
+	rasterPixelIsArea  = 1
+	userDefined        = 32767
+	linearUnitMeter    = 9001
+	citation           = "Arma 3 world coordinates (meters)|"
+)
+
+// Georeference places a raster in the coordinate system of an Arma world
+type Georeference struct {
+	// OriginX and OriginY are the world coordinates of the top left corner of the raster
+	OriginX, OriginY float64
+
+	// PixelSize is the width and height of a pixel in meters
+	PixelSize float64
+}
+
+// WriteFloat32 writes a single band float32 raster as a Cloud-Optimized GeoTIFF
+func WriteFloat32(filePath string, raster Float32Raster, geo Georeference) error {
+	noData := ""
+	if raster.HasNoData {
+		noData = strconv.FormatFloat(float64(raster.NoData), 'g', -1, 32)
+	}
+
+	return write(filePath, float32Band{raster}, geo, noData)
+}
+
+// WriteRGBA writes an image as a Cloud-Optimized GeoTIFF
+func WriteRGBA(filePath string, img *image.RGBA, geo Georeference) error {
+	return write(filePath, rgbaBand{img}, geo, "")
+}
+
+// write the band and its overviews. The directories of all resolutions are at the start
+// of the file, followed by the tiles from the smallest overview to the full resolution,
+// so that clients can read any part of the raster with few range requests.
+func write(filePath string, full band, geo Georeference, noData string) error {
+	levels := []band{full}
+	for w, h := full.size(); w > tileSize || h > tileSize; w, h = levels[len(levels)-1].size() {
+		levels = append(levels, levels[len(levels)-1].overview())
+	}
+
+	var rawSize uint64
+	for _, level := range levels {
+		cols, rows := tileCount(level)
+		f := level.format()
+		rawSize += uint64(cols*rows*tileSize*tileSize) * uint64(f.samplesPerPixel*f.bitsPerSample/8)
+	}
+	l := layout{bigTIFF: rawSize > bigTIFFThreshold}
+
+	// build directories with placeholders for the tile offsets
+	ifds := make([]*ifd, len(levels))
+	for i, level := range levels {
+		ifds[i] = newIFD(l, level, i > 0)
+	}
+	addGeoEntries(ifds[0], geo, noData)
+
+	offset := l.headerSize()
+	for _, d := range ifds {
+		d.offset = offset
+		offset += uint64(len(l.encode(d, 0)))
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// write tiles
+	if _, err = file.Seek(int64(offset), 0); err != nil {
+		return err
+	}
+	for i := len(levels) - 1; i >= 0; i-- {
+		offsets, byteCounts, err := writeTiles(file, levels[i], offset)
+		if err != nil {
+			return err
+		}
+
+		ifds[i].set(l.offsetEntry(tagTileOffsets, offsets))
+		ifds[i].set(l.offsetEntry(tagTileByteCounts, byteCounts))
+
+		for _, count := range byteCounts {
+			offset += count
+		}
+	}
+
+	// write header and directories
+	if _, err = file.Seek(0, 0); err != nil {
+		return err
+	}
+	if _, err = file.Write(l.header(ifds[0].offset)); err != nil {
+		return err
+	}
+	for i, d := range ifds {
+		var next uint64
+		if i+1 < len(ifds) {
+			next = ifds[i+1].offset
+		}
+
+		if _, err = file.Write(l.encode(d, next)); err != nil {
+			return err
+		}
+	}
+
+	return file.Close()
+}
+
+// tileCount returns the number of tile columns and rows of the band
+func tileCount(b band) (cols, rows int) {
+	w, h := b.size()
+	return (w + tileSize - 1) / tileSize, (h + tileSize - 1) / tileSize
+}
+
+func newIFD(l layout, b band, overview bool) *ifd {
+	w, h := b.size()
+	f := b.format()
+	cols, rows := tileCount(b)
+
+	bitsPerSample := make([]uint16, f.samplesPerPixel)
+	sampleFormat := make([]uint16, f.samplesPerPixel)
+	for i := range bitsPerSample {
+		bitsPerSample[i] = f.bitsPerSample
+		sampleFormat[i] = f.sampleFormat
+	}
+
+	d := &ifd{}
+	if overview {
+		d.add(longEntry(tagNewSubfileType, subfileTypeReducedImage))
+	}
+	d.add(longEntry(tagImageWidth, uint32(w)))
+	d.add(longEntry(tagImageLength, uint32(h)))
+	d.add(shortEntry(tagBitsPerSample, bitsPerSample...))
+	d.add(shortEntry(tagCompression, compressionDeflate))
+	d.add(shortEntry(tagPhotometricInterpretation, f.photometric))
+	d.add(shortEntry(tagSamplesPerPixel, f.samplesPerPixel))
+	d.add(shortEntry(tagPlanarConfiguration, planarConfigurationChunky))
+	d.add(longEntry(tagTileWidth, tileSize))
+	d.add(longEntry(tagTileLength, tileSize))
+	d.add(l.offsetEntry(tagTileOffsets, make([]uint64, cols*rows)))
+	d.add(l.offsetEntry(tagTileByteCounts, make([]uint64, cols*rows)))
+	if len(f.extraSamples) > 0 {
+		d.add(shortEntry(tagExtraSamples, f.extraSamples...))
+	}
+	d.add(shortEntry(tagSampleFormat, sampleFormat...))
+
+	return d
+}
+
+// addGeoEntries places the raster in a user-defined projected coordinate system in meters,
+// because Arma worlds don't have a real-world projection
+func addGeoEntries(d *ifd, geo Georeference, noData string) {
+	d.add(doubleEntry(tagModelPixelScale, geo.PixelSize, geo.PixelSize, 0))
+	d.add(doubleEntry(tagModelTiepoint, 0, 0, 0, geo.OriginX, geo.OriginY, 0))
+	d.add(shortEntry(tagGeoKeyDirectory,
+		1, 1, 0, 5,
+		keyGTModelType, 0, 1, modelTypeProjected,
+		keyGTRasterType, 0, 1, rasterPixelIsArea,
+		keyGTCitation, tagGeoASCIIParams, uint16(len(citation)), 0,
+		keyProjectedCSType, 0, 1, userDefined,
+		keyProjLinearUnits, 0, 1, linearUnitMeter,
+	))
+	d.add(asciiEntry(tagGeoASCIIParams, citation))
+
+	if noData != "" {
+		d.add(asciiEntry(tagGDALNoData, noData))
+	}
+}
+
+// writeTiles compresses and writes all tiles of the band row by row, starting at offset.
+// It returns the offset and size of each tile.
+func writeTiles(file *os.File, b band, offset uint64) (offsets []uint64, byteCounts []uint64, err error) {
+	cols, rows := tileCount(b)
+
+	for row := 0; row < rows; row++ {
+		// compress the tiles of a row concurrently
+		compressed := make([][]byte, cols)
+		errs := make([]error, cols)
+		waitGrp := sync.WaitGroup{}
+		for col := 0; col < cols; col++ {
+			waitGrp.Add(1)
+			go func(col int) {
+				defer waitGrp.Done()
+				compressed[col], errs[col] = deflate(b.tile(col, row))
+			}(col)
+		}
+		waitGrp.Wait()
+
+		for col := 0; col < cols; col++ {
+			if errs[col] != nil {
+				return nil, nil, errs[col]
+			}
+
+			if _, err = file.Write(compressed[col]); err != nil {
+				return nil, nil, err
+			}
+
+			offsets = append(offsets, offset)
+			byteCounts = append(byteCounts, uint64(len(compressed[col])))
+			offset += uint64(len(compressed[col]))
+		}
+	}
+
+	return offsets, byteCounts, nil
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
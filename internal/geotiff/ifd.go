@@ -0,0 +1,164 @@
+package geotiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// TIFF field types
+const (
+	typeASCII  = 2
+	typeShort  = 3
+	typeLong   = 4
+	typeDouble = 12
+	typeLong8  = 16
+)
+
+// entry is a field of an image file directory. Its data is already encoded.
+type entry struct {
+	tag   uint16
+	typ   uint16
+	count uint64
+	data  []byte
+}
+
+// ifd is an image file directory, which describes one resolution of the raster
+type ifd struct {
+	// position of the directory in the file
+	offset  uint64
+	entries []entry
+}
+
+func (d *ifd) add(e entry) {
+	d.entries = append(d.entries, e)
+}
+
+// set replaces the entry with the same tag
+func (d *ifd) set(e entry) {
+	for i := range d.entries {
+		if d.entries[i].tag == e.tag {
+			d.entries[i] = e
+			return
+		}
+	}
+	d.add(e)
+}
+
+func shortEntry(tag uint16, values ...uint16) entry {
+	data := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint16(data[2*i:], v)
+	}
+	return entry{tag, typeShort, uint64(len(values)), data}
+}
+
+func longEntry(tag uint16, values ...uint32) entry {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[4*i:], v)
+	}
+	return entry{tag, typeLong, uint64(len(values)), data}
+}
+
+func doubleEntry(tag uint16, values ...float64) entry {
+	data := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(data[8*i:], math.Float64bits(v))
+	}
+	return entry{tag, typeDouble, uint64(len(values)), data}
+}
+
+func asciiEntry(tag uint16, value string) entry {
+	data := append([]byte(value), 0)
+	return entry{tag, typeASCII, uint64(len(data)), data}
+}
+
+// layout is the flavour of the file. BigTIFF uses 64 bit offsets.
+type layout struct {
+	bigTIFF bool
+}
+
+func (l layout) headerSize() uint64 {
+	if l.bigTIFF {
+		return 16
+	}
+	return 8
+}
+
+func (l layout) header(firstIFD uint64) []byte {
+	if l.bigTIFF {
+		header := []byte{'I', 'I', 43, 0, 8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint64(header[8:], firstIFD)
+		return header
+	}
+
+	header := []byte{'I', 'I', 42, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(header[4:], uint32(firstIFD))
+	return header
+}
+
+// offsetEntry stores offsets / byte counts, which need 64 bit in BigTIFF
+func (l layout) offsetEntry(tag uint16, values []uint64) entry {
+	if l.bigTIFF {
+		data := make([]byte, 8*len(values))
+		for i, v := range values {
+			binary.LittleEndian.PutUint64(data[8*i:], v)
+		}
+		return entry{tag, typeLong8, uint64(len(values)), data}
+	}
+
+	longs := make([]uint32, len(values))
+	for i, v := range values {
+		longs[i] = uint32(v)
+	}
+	return longEntry(tag, longs...)
+}
+
+// encode the directory followed by the data of its entries, which don't fit into the entries
+func (l layout) encode(d *ifd, next uint64) []byte {
+	sort.SliceStable(d.entries, func(i, j int) bool {
+		return d.entries[i].tag < d.entries[j].tag
+	})
+
+	countSize, entrySize, valueSize := 2, 12, 4
+	if l.bigTIFF {
+		countSize, entrySize, valueSize = 8, 20, 8
+	}
+
+	var buf, external bytes.Buffer
+	externalOffset := d.offset + uint64(countSize+len(d.entries)*entrySize+valueSize)
+
+	putUint := func(b *bytes.Buffer, v uint64, size int) {
+		bytes := make([]byte, 8)
+		binary.LittleEndian.PutUint64(bytes, v)
+		b.Write(bytes[:size])
+	}
+
+	putUint(&buf, uint64(len(d.entries)), countSize)
+	for _, e := range d.entries {
+		putUint(&buf, uint64(e.tag), 2)
+		putUint(&buf, uint64(e.typ), 2)
+		putUint(&buf, e.count, valueSize)
+
+		if len(e.data) <= valueSize {
+			buf.Write(e.data)
+			buf.Write(make([]byte, valueSize-len(e.data)))
+			continue
+		}
+
+		putUint(&buf, externalOffset+uint64(external.Len()), valueSize)
+		external.Write(e.data)
+
+		// values have to start at a word boundary
+		if external.Len()%2 == 1 {
+			external.WriteByte(0)
+		}
+	}
+	putUint(&buf, next, valueSize)
+
+	buf.Write(external.Bytes())
+
+	return buf.Bytes()
+}
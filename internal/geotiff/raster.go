@@ -0,0 +1,173 @@
+package geotiff
+
+import (
+	"encoding/binary"
+	"image"
+	"math"
+)
+
+// Float32Raster is a single band raster of float32 values
+type Float32Raster struct {
+	Width, Height int
+
+	// Data holds the values row by row, starting in the top left corner
+	Data []float32
+
+	// NoData is the value of cells without data, if HasNoData is set
+	NoData    float32
+	HasNoData bool
+}
+
+// format describes how the pixels of a band are stored
+type format struct {
+	samplesPerPixel uint16
+	bitsPerSample   uint16
+	sampleFormat    uint16
+	photometric     uint16
+	extraSamples    []uint16
+}
+
+// band is one resolution of a raster, which can be cut into tiles
+type band interface {
+	size() (w, h int)
+	format() format
+
+	// tile returns the little-endian pixel data of the tile at col/row. Tiles
+	// which reach over the edge of the band are padded to the full tile size.
+	tile(col, row int) []byte
+
+	// overview returns the band with half the resolution
+	overview() band
+}
+
+type float32Band struct {
+	raster Float32Raster
+}
+
+func (b float32Band) size() (w, h int) {
+	return b.raster.Width, b.raster.Height
+}
+
+func (b float32Band) format() format {
+	return format{samplesPerPixel: 1, bitsPerSample: 32, sampleFormat: sampleFormatFloat, photometric: photometricMinIsBlack}
+}
+
+func (b float32Band) tile(col, row int) []byte {
+	data := make([]byte, tileSize*tileSize*4)
+
+	padding := float32(0)
+	if b.raster.HasNoData {
+		padding = b.raster.NoData
+	}
+
+	for y := 0; y < tileSize; y++ {
+		for x := 0; x < tileSize; x++ {
+			srcX, srcY := col*tileSize+x, row*tileSize+y
+
+			value := padding
+			if srcX < b.raster.Width && srcY < b.raster.Height {
+				value = b.raster.Data[srcY*b.raster.Width+srcX]
+			}
+
+			binary.LittleEndian.PutUint32(data[(y*tileSize+x)*4:], math.Float32bits(value))
+		}
+	}
+
+	return data
+}
+
+// overview averages each 2x2 block of cells. Cells without data are ignored.
+func (b float32Band) overview() band {
+	src := b.raster
+	dst := Float32Raster{
+		Width:     (src.Width + 1) / 2,
+		Height:    (src.Height + 1) / 2,
+		NoData:    src.NoData,
+		HasNoData: src.HasNoData,
+	}
+	dst.Data = make([]float32, dst.Width*dst.Height)
+
+	for y := 0; y < dst.Height; y++ {
+		for x := 0; x < dst.Width; x++ {
+			var sum float64
+			var count int
+
+			for srcY := 2 * y; srcY < 2*y+2 && srcY < src.Height; srcY++ {
+				for srcX := 2 * x; srcX < 2*x+2 && srcX < src.Width; srcX++ {
+					value := src.Data[srcY*src.Width+srcX]
+					if src.HasNoData && value == src.NoData {
+						continue
+					}
+					sum += float64(value)
+					count++
+				}
+			}
+
+			if count == 0 {
+				dst.Data[y*dst.Width+x] = src.NoData
+			} else {
+				dst.Data[y*dst.Width+x] = float32(sum / float64(count))
+			}
+		}
+	}
+
+	return float32Band{dst}
+}
+
+type rgbaBand struct {
+	img *image.RGBA
+}
+
+func (b rgbaBand) size() (w, h int) {
+	return b.img.Bounds().Dx(), b.img.Bounds().Dy()
+}
+
+// the pixels of an image.RGBA are alpha-premultiplied, which is an associated alpha in TIFF
+func (b rgbaBand) format() format {
+	return format{samplesPerPixel: 4, bitsPerSample: 8, sampleFormat: sampleFormatUint, photometric: photometricRGB, extraSamples: []uint16{extraSampleAssociatedAlpha}}
+}
+
+func (b rgbaBand) tile(col, row int) []byte {
+	data := make([]byte, tileSize*tileSize*4)
+
+	bounds := b.img.Bounds()
+	r := image.Rect(col*tileSize, row*tileSize, (col+1)*tileSize, (row+1)*tileSize).Add(bounds.Min).Intersect(bounds)
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		src := b.img.Pix[b.img.PixOffset(r.Min.X, y):b.img.PixOffset(r.Max.X, y)]
+		copy(data[(y-r.Min.Y)*tileSize*4:], src)
+	}
+
+	return data
+}
+
+// overview averages each 2x2 block of pixels
+func (b rgbaBand) overview() band {
+	w, h := b.size()
+	min := b.img.Bounds().Min
+	dst := image.NewRGBA(image.Rect(0, 0, (w+1)/2, (h+1)/2))
+
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			var sum [4]int
+			var count int
+
+			for srcY := 2 * y; srcY < 2*y+2 && srcY < h; srcY++ {
+				for srcX := 2 * x; srcX < 2*x+2 && srcX < w; srcX++ {
+					i := b.img.PixOffset(min.X+srcX, min.Y+srcY)
+					for c := 0; c < 4; c++ {
+						sum[c] += int(b.img.Pix[i+c])
+					}
+					count++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[i+c] = uint8((sum[c] + count/2) / count)
+			}
+		}
+	}
+
+	return rgbaBand{dst}
+}
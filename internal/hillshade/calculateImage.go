@@ -0,0 +1,97 @@
+package hillshade
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+)
+
+// multidirectionalAzimuths are the directions of the light sources in multidirectional mode
+var multidirectionalAzimuths = []float64{225, 270, 315, 360}
+
+// light is a light source
+type light struct {
+	x, y, z float64
+}
+
+// newLight creates a light source at given azimuth (degrees clockwise from north) and altitude (degrees above horizon)
+func newLight(azimuth, altitude float64) light {
+	azimuthRad := azimuth * math.Pi / 180
+	altitudeRad := altitude * math.Pi / 180
+
+	return light{
+		x: math.Sin(azimuthRad) * math.Cos(altitudeRad),
+		y: math.Cos(azimuthRad) * math.Cos(altitudeRad),
+		z: math.Sin(altitudeRad),
+	}
+}
+
+// illumination returns how much a surface with given normal is illuminated by the light (0 - 1)
+func (l light) illumination(nx, ny, nz float64) float64 {
+	return math.Max(0, nx*l.x+ny*l.y+nz*l.z)
+}
+
+func calculateImage(raster dem.EsriASCIIRaster, azimuth, altitude, zFactor float64, multidirectional bool) *image.RGBA {
+	w, h := raster.Dims()
+
+	img := image.NewRGBA(image.Rectangle{image.Point{0, 0}, image.Point{int(w), int(h)}})
+
+	lights := []light{newLight(azimuth, altitude)}
+	if multidirectional {
+		lights = []light{}
+		for _, a := range multidirectionalAzimuths {
+			lights = append(lights, newLight(a, altitude))
+		}
+	}
+
+	for col := uint(0); col < w; col++ {
+		for row := uint(0); row < h; row++ {
+			// cells without data stay transparent
+			if raster.IsNoData(col, row) {
+				continue
+			}
+
+			dzdx, dzdy := raster.Gradient(col, row)
+
+			// surface normal
+			nx, ny, nz := -zFactor*dzdx, -zFactor*dzdy, 1.0
+			length := math.Sqrt(nx*nx + ny*ny + nz*nz)
+			nx, ny, nz = nx/length, ny/length, nz/length
+
+			var shade float64
+			if multidirectional {
+				shade = multidirectionalShade(lights, nx, ny, nz)
+			} else {
+				shade = lights[0].illumination(nx, ny, nz)
+			}
+
+			gray := uint8(math.Round(shade * 255))
+			img.SetRGBA(int(col), int(row), color.RGBA{gray, gray, gray, 255})
+		}
+	}
+
+	return img
+}
+
+// multidirectionalShade combines the illumination of multiple light sources. Each light is
+// weighted by how perpendicular it is to the aspect of the surface, which emphasizes
+// features that the single light source doesn't show (similar to gdaldem -multidirectional).
+func multidirectionalShade(lights []light, nx, ny, nz float64) float64 {
+	// flat surfaces are lit equally by all lights
+	if nx == 0 && ny == 0 {
+		return lights[0].illumination(nx, ny, nz)
+	}
+
+	aspect := math.Atan2(nx, ny)
+
+	var shade, weights float64
+	for i, l := range lights {
+		weight := math.Pow(math.Sin(aspect-multidirectionalAzimuths[i]*math.Pi/180), 2)
+		shade += weight * l.illumination(nx, ny, nz)
+		weights += weight
+	}
+
+	return shade / weights
+}
@@ -0,0 +1,98 @@
+package hillshade
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+	"github.com/gruppe-adler/meh-utils/internal/metajson"
+	"github.com/gruppe-adler/meh-utils/internal/tilejson"
+	"github.com/gruppe-adler/meh-utils/internal/tilewriter"
+	"github.com/gruppe-adler/meh-utils/internal/utils"
+	"github.com/gruppe-adler/meh-utils/internal/validate"
+)
+
+// Run is the program's entrypoint
+func Run(flagSet *flag.FlagSet) {
+
+	var timer time.Time
+	start := time.Now()
+
+	outputPtr := flagSet.String("out", "", "Path to output directory (or output file for -format mbtiles / pmtiles)")
+	inputPtr := flagSet.String("in", "", "Path to grad_meh map directory")
+	formatPtr := flagSet.String("format", tilewriter.Directory, fmt.Sprintf("Output format (%s)", strings.Join(tilewriter.Formats, ", ")))
+	azimuthPtr := flagSet.Float64("azimuth", 315, "Direction of the light source in degrees clockwise from north")
+	altitudePtr := flagSet.Float64("altitude", 45, "Altitude of the light source in degrees above the horizon")
+	zFactorPtr := flagSet.Float64("z_factor", 1, "Vertical exaggeration")
+	multidirectionalPtr := flagSet.Bool("multidirectional", false, "Combine light sources from multiple directions (ignores -azimuth)")
+
+	flagSet.Parse(os.Args[2:])
+
+	// make sure both flags are present
+	if *outputPtr == "" || *inputPtr == "" {
+		flagSet.PrintDefaults()
+		os.Exit(1)
+	}
+
+	// create tile writer (makes sure the output path is valid)
+	writer, err := tilewriter.New(*formatPtr, *outputPtr, "png")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// validate input directory structure
+	err = validate.MehDirectory(*inputPtr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("✔️  Validated input directory structure")
+
+	// load meta.json
+	timer = time.Now()
+	fmt.Println("▶️  Loading meta.json")
+	meta, err := metajson.Read(path.Join(*inputPtr, "meta.json"))
+	if err != nil {
+		log.Fatal(errors.New("Failed to read meta.json"))
+	}
+	fmt.Println("✔️  Loaded meta.json in", time.Now().Sub(timer).String())
+
+	// load DEM
+	timer = time.Now()
+	fmt.Println("▶️  Loading DEM")
+	raster := dem.Read(path.Join(*inputPtr, "dem.asc.gz"))
+	fmt.Println("✔️  Loaded DEM in", time.Now().Sub(timer).String())
+
+	// calculating image
+	timer = time.Now()
+	fmt.Println("▶️  Calculating hillshade from DEM")
+	img := calculateImage(raster, *azimuthPtr, *altitudePtr, *zFactorPtr, *multidirectionalPtr)
+	fmt.Println("✔️  Calculated hillshade in", time.Now().Sub(timer).String())
+
+	// calculate max LOD
+	maxLod := utils.CalcMaxLodFromImage(img)
+	fmt.Println("ℹ️  Calculated max lod:", maxLod)
+
+	// build tiles
+	timer = time.Now()
+	fmt.Println("▶️  Building tiles")
+	utils.BuildTilePyramid(maxLod, img, utils.RGBAResampler{}, writer)
+	fmt.Println("✔️  Built hillshade tiles in", time.Now().Sub(timer).String())
+
+	// write tile.json / metadata
+	timer = time.Now()
+	fmt.Println("▶️  Writing tileset metadata")
+	err = writer.Close(tilejson.New(maxLod, meta, "Hillshade", []string{}))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("✔️  Wrote tileset metadata in", time.Now().Sub(timer).String())
+
+	fmt.Printf("\n    🎉  Finished in %s\n", time.Now().Sub(start).String())
+}
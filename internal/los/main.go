@@ -0,0 +1,68 @@
+package los
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+	"github.com/gruppe-adler/meh-utils/internal/utils"
+	"github.com/gruppe-adler/meh-utils/internal/validate"
+)
+
+// Run is the program's entrypoint. It only prints the result, so that it can be used in scripts.
+// The exit code is 0 if the target is visible and 2 if it is not.
+func Run(flagSet *flag.FlagSet) {
+
+	inputPtr := flagSet.String("in", "", "Path to grad_meh map directory")
+	demPtr := flagSet.String("dem", "", "Path to DEM (ESRI ASCII grid, XYZ, GeoTIFF or ESRI float grid), defaults to the dem.asc.gz of the grad_meh map directory")
+	fromPtr := flagSet.String("from", "", "Position of the observer (x,y)")
+	toPtr := flagSet.String("to", "", "Position of the target (x,y)")
+	eyeHeightPtr := flagSet.Float64("eye_height", 1.7, "Height of the observer's eyes above the ground in meters")
+	targetHeightPtr := flagSet.Float64("target_height", 1.7, "Height of the target above the ground in meters")
+
+	flagSet.Parse(os.Args[2:])
+
+	// make sure all flags are present
+	if *inputPtr == "" || *fromPtr == "" || *toPtr == "" {
+		flagSet.PrintDefaults()
+		os.Exit(1)
+	}
+
+	from, err := utils.ParseCoordinate(*fromPtr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	to, err := utils.ParseCoordinate(*toPtr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	demPath := *demPtr
+	if demPath == "" {
+		demPath = path.Join(*inputPtr, "dem.asc.gz")
+	}
+
+	// validate input directory structure
+	err = validate.MehDirectoryWithDEM(*inputPtr, demPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	raster := dem.Read(demPath)
+
+	visible, blockedX, blockedY, ok := raster.LineOfSight(from[0], from[1], *eyeHeightPtr, to[0], to[1], *targetHeightPtr)
+	if !ok {
+		log.Fatal(errors.New("Observer or target is outside of the DEM"))
+	}
+
+	if !visible {
+		fmt.Printf("blocked at %.1f,%.1f\n", blockedX, blockedY)
+		os.Exit(2)
+	}
+
+	fmt.Println("visible")
+}
@@ -0,0 +1,38 @@
+package mvt
+
+import (
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+
+	dem "github.com/gruppe-adler/meh-utils/internal/dem"
+)
+
+// buildBathymetry builds the polygons between each two consecutive depth breaks and the depth
+// contours of the given (negative) levels. The lines have to include the contour lines of all
+// depth levels.
+func buildBathymetry(lines map[float64][]orb.LineString, depthBreaks []float64, depthLevels []float64, raster *dem.EsriASCIIRaster) *geojson.FeatureCollection {
+	bathymetry := geojson.NewFeatureCollection()
+
+	// depth areas
+	for i := 1; i < len(depthBreaks); i++ {
+		minDepth := depthBreaks[i-1]
+		maxDepth := depthBreaks[i]
+
+		for _, f := range buildAreas(raster, -maxDepth, -minDepth).Features {
+			f.Properties["min_depth"] = minDepth
+			f.Properties["max_depth"] = maxDepth
+			bathymetry.Append(f)
+		}
+	}
+
+	// depth contours
+	for _, level := range depthLevels {
+		for _, line := range lines[level] {
+			f := geojson.NewFeature(line)
+			f.Properties["depth"] = -level
+			bathymetry.Append(f)
+		}
+	}
+
+	return bathymetry
+}
@@ -0,0 +1,181 @@
+package mvt
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+const (
+	// distance between two labels on the same contour line (in meters)
+	contourLabelSpacing = 1000.0
+
+	// half the length of the part of a contour line a label covers (in meters)
+	contourLabelHalfLength = 40.0
+
+	// maximum change of direction within the part of a contour line a label covers (in degrees)
+	contourLabelMaxBend = 25.0
+)
+
+// buildContourLabels places label points at regular intervals along all contour lines, which
+// are index contours of any contour layer. Labels are only placed where the contour line is
+// fairly straight and never on the edge of a tile, because MapLibre cuts them off there.
+func buildContourLabels(contours *geojson.FeatureCollection, contourSettings []layerSetting, worldSize float64) *geojson.FeatureCollection {
+	labels := geojson.NewFeatureCollection()
+
+	indexIntervals := []float64{}
+	for _, setting := range contourSettings {
+		if setting.IndexInterval != nil {
+			indexIntervals = append(indexIntervals, *setting.IndexInterval)
+		}
+	}
+
+	// the edges of the tiles of lower LODs are a subset of the edges of the highest LOD
+	tileWidth := worldSize / math.Pow(2, float64(calcMaxLod(worldSize)))
+
+	for _, f := range contours.Features {
+		demElevation := f.Properties["dem_elevation"].(float64)
+
+		isIndex := false
+		for _, interval := range indexIntervals {
+			isIndex = isIndex || isMultipleOf(demElevation, interval)
+		}
+		if !isIndex {
+			continue
+		}
+
+		line := f.Geometry.(orb.LineString)
+		elevation := f.Properties["elevation"].(float64)
+
+		for _, label := range placeContourLabels(line, tileWidth) {
+			label.Properties["elevation"] = strconv.FormatFloat(math.Round(elevation*100)/100, 'f', -1, 64)
+			label.Properties["dem_elevation"] = demElevation
+			labels.Append(label)
+		}
+	}
+
+	return labels
+}
+
+// placeContourLabels places labels every contourLabelSpacing meters along the line. If the line
+// is too curvy or crosses a tile edge at a position, the next suitable position is used instead.
+func placeContourLabels(line orb.LineString, tileWidth float64) []*geojson.Feature {
+	labels := []*geojson.Feature{}
+
+	// cumulative length of the line at each point
+	lengths := make([]float64, len(line))
+	for i := 1; i < len(line); i++ {
+		lengths[i] = lengths[i-1] + distance(line[i-1], line[i])
+	}
+	total := lengths[len(lengths)-1]
+
+	if total < 2*contourLabelHalfLength {
+		return labels
+	}
+
+	// the first label is centered in the first spacing, so short lines still get one label
+	for target := math.Min(contourLabelSpacing, total) / 2; target < total; target += contourLabelSpacing {
+
+		// search for a suitable position up to half the spacing away from the target
+		for offset := 0.0; offset < contourLabelSpacing/2; offset += contourLabelHalfLength / 2 {
+			label, ok := contourLabelAt(line, lengths, target+offset, tileWidth)
+			if !ok && offset > 0 {
+				label, ok = contourLabelAt(line, lengths, target-offset, tileWidth)
+			}
+
+			if ok {
+				labels = append(labels, label)
+				break
+			}
+		}
+	}
+
+	return labels
+}
+
+// contourLabelAt creates a label at the position of the line, which is pos meters away from
+// its start. It fails if the label would bend too much or cross a tile edge.
+func contourLabelAt(line orb.LineString, lengths []float64, pos float64, tileWidth float64) (*geojson.Feature, bool) {
+	if pos-contourLabelHalfLength < 0 || pos+contourLabelHalfLength > lengths[len(lengths)-1] {
+		return nil, false
+	}
+
+	start := pointAtLength(line, lengths, pos-contourLabelHalfLength)
+	center := pointAtLength(line, lengths, pos)
+	end := pointAtLength(line, lengths, pos+contourLabelHalfLength)
+
+	// the label must be within a single tile
+	tile := func(p orb.Point) [2]int {
+		return [2]int{int(math.Floor(p[0] / tileWidth)), int(math.Floor(p[1] / tileWidth))}
+	}
+	if tile(start) != tile(center) || tile(end) != tile(center) {
+		return nil, false
+	}
+
+	// the line must not bend too much below the label, which is checked by comparing
+	// the direction of the first and second half and all points in between
+	inDirection := math.Atan2(center[1]-start[1], center[0]-start[0])
+	outDirection := math.Atan2(end[1]-center[1], end[0]-center[0])
+	if angleBetween(inDirection, outDirection) > contourLabelMaxBend {
+		return nil, false
+	}
+	direction := math.Atan2(end[1]-start[1], end[0]-start[0])
+	for i := range line {
+		if lengths[i] <= pos-contourLabelHalfLength || lengths[i] >= pos+contourLabelHalfLength {
+			continue
+		}
+
+		prev := start
+		if i > 0 && lengths[i-1] > pos-contourLabelHalfLength {
+			prev = line[i-1]
+		}
+		if angleBetween(direction, math.Atan2(line[i][1]-prev[1], line[i][0]-prev[0])) > contourLabelMaxBend {
+			return nil, false
+		}
+	}
+
+	// the angle is clockwise in screen coordinates (y is pointing down) and the
+	// text must never be upside down
+	angle := -direction * 180 / math.Pi
+	if angle > 90 {
+		angle -= 180
+	}
+	if angle <= -90 {
+		angle += 180
+	}
+
+	label := geojson.NewFeature(center)
+	label.Properties["angle"] = angle
+
+	return label, true
+}
+
+// pointAtLength returns the point of the line, which is length meters away from its start
+func pointAtLength(line orb.LineString, lengths []float64, length float64) orb.Point {
+	for i := 1; i < len(line); i++ {
+		if lengths[i] < length {
+			continue
+		}
+
+		segment := lengths[i] - lengths[i-1]
+		if segment == 0 {
+			return line[i]
+		}
+
+		return lerp(line[i-1], line[i], (length-lengths[i-1])/segment)
+	}
+
+	return line[len(line)-1]
+}
+
+// angleBetween returns the angle between two directions (in radians) in degrees
+func angleBetween(a, b float64) float64 {
+	diff := math.Abs(math.Mod(a-b, 2*math.Pi))
+	if diff > math.Pi {
+		diff = 2*math.Pi - diff
+	}
+
+	return diff * 180 / math.Pi
+}
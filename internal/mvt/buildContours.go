@@ -1,26 +1,31 @@
 package mvt
 
 import (
-	"context"
-	"runtime"
-	"sync"
+	"math"
+	"sort"
 
 	"github.com/paulmach/orb"
 	"github.com/paulmach/orb/geojson"
-	"github.com/paulmach/orb/planar"
-	"golang.org/x/sync/semaphore"
 
 	dem "github.com/gruppe-adler/meh-utils/internal/dem"
 )
 
-func buildContours(raster *dem.EsriASCIIRaster, elevOffset float64, worldSize float64, layers *map[string]*geojson.FeatureCollection) {
+func buildContours(raster *dem.EsriASCIIRaster, elevOffset float64, worldSize float64, layerSettings []layerSetting, smoothing contourSmoothing, layers *map[string]*geojson.FeatureCollection) {
+	contourSettings := contourLayerSettings(layerSettings)
+	bandBreaks := layerBreaks(layerSettings, "elevation_bands")
+	depthBreaks := layerBreaks(layerSettings, "bathymetry")
+	bathymetrySetting, hasBathymetry := findLayerSetting(layerSettings, "bathymetry")
 
 	// find max / min elevation in DEM
 	maxElevation := float64(0)
 	minElevation := float64(10000)
 	for row := uint(0); row < raster.Nrows; row++ {
 		for col := uint(0); col < raster.Ncols; col++ {
-			d := raster.Data[row][col]
+			if raster.IsNoData(col, row) {
+				continue
+			}
+
+			d := raster.Z(col, row)
 
 			if d < minElevation {
 				minElevation = d
@@ -32,293 +37,146 @@ func buildContours(raster *dem.EsriASCIIRaster, elevOffset float64, worldSize fl
 		}
 	}
 
-	waitGrp := sync.WaitGroup{}
-
-	contours := geojson.NewFeatureCollection()
-
-	waterLines := []orb.LineString{}
-	sem := semaphore.NewWeighted(int64(runtime.NumCPU()))
-	var contoursMux = sync.Mutex{}
-
-	for elevation := int(minElevation - 1); elevation < int(maxElevation+1); elevation++ {
-		waitGrp.Add(1)
-		sem.Acquire(context.Background(), 1)
-		go func(elev int) {
-			defer waitGrp.Done()
-			defer sem.Release(1)
-
-			lines := dem.MarchingSquares(raster, float64(elev))
-
-			if elev == 0 {
-				waterLines = lines
-			}
+	// collect intervals of all contour layers
+	intervals := make([]float64, len(contourSettings))
+	for i, setting := range contourSettings {
+		intervals[i] = *setting.ContourInterval
+	}
 
-			contoursMux.Lock()
-			// add lines to correct feature collection
-			for _, line := range lines {
-				f := geojson.NewFeature(line)
-				f.Properties["elevation"] = float64(elev) + elevOffset
-				f.Properties["dem_elevation"] = elev
-				contours.Append(f)
+	// depth contours are below sea level only
+	depthLevels := []float64{}
+	if hasBathymetry && bathymetrySetting.DepthInterval != nil {
+		for _, level := range contourLevels(minElevation-1, math.Min(maxElevation+1, 0), []float64{*bathymetrySetting.DepthInterval}) {
+			if level < 0 {
+				depthLevels = append(depthLevels, level)
 			}
-			contoursMux.Unlock()
-
-		}(elevation)
+		}
 	}
 
-	waitGrp.Wait()
-
-	(*layers)["contours"] = contours
-	(*layers)["contours/01"] = geojson.NewFeatureCollection()
-	(*layers)["contours/05"] = geojson.NewFeatureCollection()
-	(*layers)["contours/10"] = geojson.NewFeatureCollection()
-	(*layers)["contours/50"] = geojson.NewFeatureCollection()
-	(*layers)["contours/100"] = geojson.NewFeatureCollection()
+	contourLevels := contourLevels(minElevation-1, maxElevation+1, intervals)
 
-	// build water
-	if len(waterLines) > 0 {
-		(*layers)["water"] = buildWater(waterLines, worldSize, raster)
+	// the lines of the depth contours are needed for the bathymetry, even if they aren't contour levels
+	levels := append([]float64{}, contourLevels...)
+	for _, level := range depthLevels {
+		if !containsLevel(levels, level) {
+			levels = append(levels, level)
+		}
 	}
 
-}
-
-func buildWater(lines []orb.LineString, worldSize float64, raster *dem.EsriASCIIRaster) *geojson.FeatureCollection {
-	rings := make(map[int]orb.Ring)
-
-	// normalize rings
-	for index, line := range lines {
-		r := orb.Ring(line.Clone())
-
-		// close all rings
-		if !r.Closed() {
-			// non closed rings usually occur, when a contour line meets a map edge
-			// this makes things a little tricks, because what happens if for example
-			// the beginning of the line is on the top edge of the map and the end is
-			// on the left edge (see Chernarus) we can't just connect the two points
-			// (begining and end) and call it a day. We have to insert another point
-			// (in the upper-left corner) in between the beginning and the end.
-			start := r[0]
-			end := r[len(r)-1]
-
-			const (
-				TOP_EDGE    = 0b0001
-				LEFT_EDGE   = 0b0010
-				BOTTOM_EDGE = 0b0100
-				RIGHT_EDGE  = 0b1000
-			)
-
-			// returns bitmask, on which world edges point is
-			// first bit  -> top edge
-			// second bit -> right edge
-			// third bit  -> bottom edge
-			// fourth bit -> left edge
-			findEdges := func(point orb.Point) byte {
-				edges := byte(0)
-
-				// - cellsize, because Arma actually does the same thing (see https://i.imgur.com/u0sO4Si.png)
-				if point[1] == worldSize-raster.CellSize {
-					// TOP
-					edges |= TOP_EDGE
-				}
-				if point[0] == worldSize-raster.CellSize {
-					// RIGHT
-					edges |= RIGHT_EDGE
-				}
-				if point[1] == 0 {
-					// BOTTOM
-					edges |= BOTTOM_EDGE
-				}
-				if point[0] == 0 {
-					// LEFT
-					edges |= LEFT_EDGE
-				}
-
-				return edges
-			}
-
-			startEdges := findEdges(start)
-			endEdges := findEdges(end)
-
-			if startEdges&endEdges == 0 {
-				// start and end do NOT share an edge, so we
-				// need to insert the correct point in between
-
-				isTop := func(edges byte) bool { return edges&TOP_EDGE > 0 }
-				isRight := func(edges byte) bool { return edges&RIGHT_EDGE > 0 }
-				isBottom := func(edges byte) bool { return edges&BOTTOM_EDGE > 0 }
-				isLeft := func(edges byte) bool { return edges&LEFT_EDGE > 0 }
-
-				if isTop(startEdges) && isRight(endEdges) || isRight(startEdges) && isTop(endEdges) {
-					r = append(r, orb.Point{worldSize, worldSize})
-				}
-
-				if isBottom(startEdges) && isRight(endEdges) || isRight(startEdges) && isBottom(endEdges) {
-					r = append(r, orb.Point{worldSize, 0})
-				}
-
-				if isBottom(startEdges) && isLeft(endEdges) || isLeft(startEdges) && isBottom(endEdges) {
-					r = append(r, orb.Point{0, 0})
-				}
+	contours := geojson.NewFeatureCollection()
+	depthLines := make(map[float64][]orb.LineString)
 
-				if isTop(startEdges) && isLeft(endEdges) || isLeft(startEdges) && isTop(endEdges) {
-					r = append(r, orb.Point{0, worldSize})
-				}
-			}
+	// all levels are traced in a single pass over the DEM
+	levelLines := dem.MarchingSquaresLevels(raster, levels)
 
-			r = append(r, start)
+	for i, elev := range levels {
+		lines := levelLines[i]
 
+		if containsLevel(depthLevels, elev) {
+			depthLines[elev] = lines
 		}
 
-		// make sure the ring is winding order = clockwise
-		// https://stackoverflow.com/a/1165943
-		sum := float64(0)
-		for i := 1; i < len(r); i++ {
-			p1 := r[i-1]
-			p2 := r[i]
-			sum += (p2[0] - p1[0]) * (p2[1] + p1[1])
+		if !containsLevel(contourLevels, elev) {
+			continue
 		}
-		if sum < 0 {
-			r.Reverse()
-		}
-
-		rings[index] = r
-	}
 
-	// ring-id -> array of rings which this rings contains
-	ringsByParent := make(map[int][]int)
-
-	// ring-id -> number of parents
-	ringNumberOfParents := make(map[int]int)
-
-	// fill ringsByParent and ringNumberOfParents
-	for id, ring := range rings {
-		childIndices := []int{}
-
-		for childID, childRing := range rings {
-			// we don't need to compare the ring to itself
-			if id == childID {
-				continue
-			}
-
-			if ringContainsRing(&ring, &childRing) {
-				childIndices = append(childIndices, childID)
-				ringNumberOfParents[childID]++
-			}
+		// add lines to correct feature collection
+		for _, line := range lines {
+			f := geojson.NewFeature(line)
+			f.Properties["elevation"] = elev + elevOffset
+			f.Properties["dem_elevation"] = elev
+			contours.Append(f)
 		}
-
-		ringsByParent[id] = childIndices
 	}
 
-	// find pos in DEM which is "significally" above / below 0
-	col := uint(0)
-	row := uint(0)
-	height := raster.Z(col, row)
-	for height < 0.1 && height > -0.1 {
-		col++
+	// the labels are placed along the smoothed lines
+	smoothContours(contours.Features, smoothing)
 
-		if col >= raster.Ncols {
-			row++
-			col = 0
-		}
-
-		height = raster.Z(col, row)
+	(*layers)["contours"] = contours
+	for _, setting := range contourSettings {
+		(*layers)[setting.Layer] = geojson.NewFeatureCollection()
 	}
-	point := orb.Point{raster.X(col), raster.Y(row)}
+	(*layers)["contour_labels"] = buildContourLabels(contours, contourSettings, worldSize)
 
-	// find number of rings which contain point
-	numOfContainingRings := 0
-	for _, ring := range rings {
-		if planar.RingContains(ring, point) {
-			numOfContainingRings++
-		}
+	// build water
+	water := buildWater(raster)
+	if len(water.Features) > 0 {
+		(*layers)["water"] = water
 	}
 
-	// A: height > 0
-	// B: numOfContainingRings%2 == 0
-	//
-	// if point is above 0 and the number of rings, which contain point is..
-	//     ...even -> map isn't island (A && B)
-	//     ...odd -> map is island (A && !B)
-	// if point is below 0 and the number of rings, which contain point is..
-	//     ...even -> map is island (!A && B)
-	//     ...odd -> map isn't island (!A && !B)
-	isIsland := (height > 0) != (numOfContainingRings%2 == 0)
-
-	if isIsland {
-		wholeMapRingIndex := -1
-
-		wholeMapRing := orb.Ring{
-			orb.Point{0, 0},
-			orb.Point{0, worldSize},
-			orb.Point{worldSize, worldSize},
-			orb.Point{worldSize, 0},
-			orb.Point{0, 0},
-		}
-
-		childRings := make([]int, len(rings))
-		for id := range rings {
-			childRings[id] = id
+	// build elevation bands
+	if len(bandBreaks) > 1 {
+		(*layers)["elevation_bands"] = buildElevationBands(bandBreaks, elevOffset, raster)
+	}
 
-			ringNumberOfParents[id]++
+	// build bathymetry
+	if len(depthBreaks) > 1 || len(depthLevels) > 0 {
+		bathymetry := buildBathymetry(depthLines, depthBreaks, depthLevels, raster)
+		if len(bathymetry.Features) > 0 {
+			(*layers)["bathymetry"] = bathymetry
 		}
-
-		ringsByParent[wholeMapRingIndex] = childRings
-		rings[wholeMapRingIndex] = wholeMapRing
 	}
 
-	maxNumOfParents := 0
+}
 
-	// make sure rings are right winding order
-	for id, ring := range rings {
-		numOfParents := ringNumberOfParents[id]
+// contourLevels returns all multiples of the intervals between min and max (inclusive) in ascending order
+func contourLevels(min, max float64, intervals []float64) []float64 {
+	found := make(map[float64]bool)
+	levels := []float64{}
 
-		if numOfParents > maxNumOfParents {
-			maxNumOfParents = numOfParents
-		}
+	for _, interval := range intervals {
+		for k := math.Ceil(min / interval); k*interval <= max; k++ {
+			level := roundLevel(k * interval)
 
-		if numOfParents%2 == 1 {
-			ring.Reverse()
+			if !found[level] {
+				found[level] = true
+				levels = append(levels, level)
+			}
 		}
 	}
 
-	waterFeatureCollection := geojson.NewFeatureCollection()
-
-	// create actual features
-	for level := maxNumOfParents - maxNumOfParents%2; level >= 0; level = level - 2 {
-		for ringID, ring := range rings {
-			if ringNumberOfParents[ringID] != level {
-				continue
-			}
+	sort.Float64s(levels)
 
-			poly := orb.Polygon{ring}
-			delete(rings, ringID)
+	return levels
+}
 
-			// add all holes that are contained in current ring
-			holes := ringsByParent[ringID]
-			for _, id := range holes {
-				hole, found := rings[id]
+// roundLevel rounds away floating point errors of a level (e.g. 3*0.1 = 0.30000000000000004)
+func roundLevel(level float64) float64 {
+	return math.Round(level*1e6) / 1e6
+}
 
-				if found {
-					poly = append(poly, hole)
-					delete(rings, id)
-				}
-			}
+// isMultipleOf checks whether the level is a multiple of the interval
+func isMultipleOf(level float64, interval float64) bool {
+	return math.Abs(math.Remainder(level, interval)) < 1e-6
+}
 
-			waterFeatureCollection.Append(geojson.NewFeature(poly))
+func containsLevel(levels []float64, level float64) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
 		}
 	}
+	return false
+}
 
-	return waterFeatureCollection
+// buildWater builds the polygons of the area at or below sea level
+func buildWater(raster *dem.EsriASCIIRaster) *geojson.FeatureCollection {
+	return buildAreas(raster, math.Inf(-1), 0)
 }
 
-func ringContainsRing(parent *orb.Ring, child *orb.Ring) bool {
-	for _, point := range *child {
-		contains := planar.RingContains(*parent, point)
+// buildAreas builds the polygons of the area, in which the elevation is above min and at most max.
+// The outer rings are clockwise and the holes counter-clockwise, because the y axis is flipped in
+// vector tiles, which expect the opposite in tile coordinates.
+func buildAreas(raster *dem.EsriASCIIRaster, min, max float64) *geojson.FeatureCollection {
+	areaFeatureCollection := geojson.NewFeatureCollection()
 
-		if !contains {
-			return false
+	for _, poly := range dem.MarchingSquaresPolygons(raster, min, max) {
+		for _, ring := range poly {
+			ring.Reverse()
 		}
+
+		areaFeatureCollection.Append(geojson.NewFeature(poly))
 	}
 
-	return true
+	return areaFeatureCollection
 }
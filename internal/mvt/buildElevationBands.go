@@ -0,0 +1,25 @@
+package mvt
+
+import (
+	"github.com/paulmach/orb/geojson"
+
+	dem "github.com/gruppe-adler/meh-utils/internal/dem"
+)
+
+// buildElevationBands builds the polygons between each two consecutive breaks
+func buildElevationBands(breaks []float64, elevOffset float64, raster *dem.EsriASCIIRaster) *geojson.FeatureCollection {
+	bands := geojson.NewFeatureCollection()
+
+	for i := 1; i < len(breaks); i++ {
+		min := breaks[i-1]
+		max := breaks[i]
+
+		for _, f := range buildAreas(raster, min, max).Features {
+			f.Properties["min"] = min + elevOffset
+			f.Properties["max"] = max + elevOffset
+			bands.Append(f)
+		}
+	}
+
+	return bands
+}
@@ -17,10 +17,10 @@ func buildMounts(raster *dem.EsriASCIIRaster, elevOffset float64, layers *map[st
 	// for all cells (except edges)
 	for row := uint(1); row < raster.Nrows-1; row++ {
 		for col := uint(1); col < raster.Ncols-1; col++ {
-			elevation := raster.Data[row][col]
+			elevation := raster.Z(col, row)
 
 			// we'll only create mounts for peaks, which are above the water level
-			if elevation <= 0 {
+			if elevation <= 0 || raster.IsNoData(col, row) {
 				continue
 			}
 
@@ -39,12 +39,12 @@ func buildMounts(raster *dem.EsriASCIIRaster, elevOffset float64, layers *map[st
 						break
 					}
 
-					// we don't want to compare to the reference cell
-					if row == compareRow && col == compareCol {
+					// we don't want to compare to the reference cell or cells without data
+					if (row == compareRow && col == compareCol) || raster.IsNoData(compareCol, compareRow) {
 						continue
 					}
 
-					compareElev := raster.Data[compareRow][compareCol]
+					compareElev := raster.Z(compareCol, compareRow)
 
 					// we'll count same elvation as both a high and low neighbour because we
 					// don't want to generate a "mount" for cells that are in the middle of a plane
@@ -4,11 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math"
-	"os"
-	"path"
-	"regexp"
 	"runtime"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -24,12 +20,12 @@ import (
 	"github.com/paulmach/orb/planar"
 	"github.com/paulmach/orb/project"
 
-	"github.com/gruppe-adler/meh-utils/internal/utils"
+	"github.com/gruppe-adler/meh-utils/internal/tilewriter"
 )
 
 const tileSize = mvt.DefaultExtent
 
-func buildVectorTiles(outputPath string, collectionsPtr *map[string]*geojson.FeatureCollection, maxLod uint8, worldSize float64, layerSettings *[]layerSetting) {
+func buildVectorTiles(writer tilewriter.Writer, collectionsPtr *map[string]*geojson.FeatureCollection, maxLod uint8, worldSize float64, layerSettings *[]layerSetting) {
 	allLayers := make(map[string]*mvt.Layer)
 
 	// set layer version to v2
@@ -50,18 +46,8 @@ func buildVectorTiles(outputPath string, collectionsPtr *map[string]*geojson.Fea
 	})
 
 	for lod := maxLod; lod >= 0; lod-- {
-		lodDir := path.Join(outputPath, fmt.Sprintf("%d", lod))
 		start := time.Now()
 
-		// create LOD directory
-		if !utils.IsDirectory(path.Dir(lodDir)) {
-			err := os.MkdirAll(lodDir, os.ModePerm)
-			if err != nil {
-				fmt.Println(err)
-				return
-			}
-		}
-
 		// project from last LOD to this LOD
 		if lod != maxLod {
 			projectLayersInPlace(allLayers, func(p orb.Point) orb.Point {
@@ -88,7 +74,7 @@ func buildVectorTiles(outputPath string, collectionsPtr *map[string]*geojson.Fea
 			}
 
 			switch layer.Name {
-			case "bunker", "chapel", "church", "cross", "fuelstation", "lighthouse", "rock", "shipwreck", "transmitter", "watertower", "fortress", "fountain", "view-tower", "quay", "hospital", "busstop", "stack", "ruin", "tourism", "powersolar", "powerwave", "powerwind", "tree", "bush":
+			case "bunker", "chapel", "church", "cross", "fuelstation", "lighthouse", "rock", "shipwreck", "transmitter", "watertower", "fortress", "fountain", "view-tower", "quay", "hospital", "busstop", "stack", "ruin", "tourism", "powersolar", "powerwave", "powerwind", "tree", "bush", "contour_labels":
 				continue
 			case "mount":
 				simplifyMounts(layer, 1000)
@@ -99,14 +85,18 @@ func buildVectorTiles(outputPath string, collectionsPtr *map[string]*geojson.Fea
 			case "contours":
 				layer.Simplify(simplify.DouglasPeucker(5))
 				layer.RemoveEmpty(100, 0)
-			case "water":
+			case "water", "elevation_bands", "bathymetry":
 				layer.Simplify(simplify.DouglasPeucker(5))
 				layer.RemoveEmpty(100, 0)
 
 				// RemoveEmpty does not remove rings of holes smaller
 				// than threshold so we'll have to do that ourselves
 				for _, feature := range layer.Features {
-					poly := feature.Geometry.(orb.Polygon)
+					// bathymetry also includes the depth contours
+					poly, ok := feature.Geometry.(orb.Polygon)
+					if !ok {
+						continue
+					}
 
 					keepCount := 0
 					for _, r := range poly {
@@ -131,9 +121,10 @@ func buildVectorTiles(outputPath string, collectionsPtr *map[string]*geojson.Fea
 		}
 
 		lodLayers := findLODLayers(allLayers, layerSettings, lod, maxLod)
-		fillContourLayers(lodLayers, allLayers["contours"])
+		fillContourLayers(lodLayers, allLayers["contours"], layerSettings)
+		fillContourLabels(lodLayers, layerSettings)
 
-		buildLODVectorTiles(lod, lodDir, lodLayers)
+		buildLODVectorTiles(lod, lodLayers, writer)
 
 		fmt.Println("    ✔️  Finished tiles for LOD", lod, "in", time.Now().Sub(start).String())
 
@@ -145,7 +136,7 @@ func buildVectorTiles(outputPath string, collectionsPtr *map[string]*geojson.Fea
 	}
 }
 
-func buildLODVectorTiles(lod uint8, lodDir string, layers mvt.Layers) {
+func buildLODVectorTiles(lod uint8, layers mvt.Layers, writer tilewriter.Writer) {
 	// how many tiles one row / col has
 	tilesPerRowCol := uint32(math.Pow(2, float64(lod)))
 
@@ -154,16 +145,6 @@ func buildLODVectorTiles(lod uint8, lodDir string, layers mvt.Layers) {
 	sem := semaphore.NewWeighted(int64(runtime.NumCPU()))
 
 	for col := uint32(0); col < tilesPerRowCol; col++ {
-		// create column directory
-		colPath := path.Join(lodDir, fmt.Sprintf("%d", col))
-		if !utils.IsDirectory(colPath) {
-			err := os.MkdirAll(colPath, os.ModePerm)
-			if err != nil {
-				fmt.Println(err)
-				return
-			}
-		}
-
 		for row := uint32(0); row < tilesPerRowCol; row++ {
 			tileWaitGroup.Add(1)
 			go func(c, r uint32) {
@@ -179,8 +160,7 @@ func buildLODVectorTiles(lod uint8, lodDir string, layers mvt.Layers) {
 
 				sem.Release(1)
 
-				tilePath := path.Join(colPath, fmt.Sprintf("%d.pbf", r))
-				err = writeTile(tilePath, data)
+				err = writer.WriteTile(lod, c, r, data)
 				if err != nil {
 					fmt.Printf("Error while writing tile %d/%d/%d\n", lod, c, r)
 					return
@@ -236,44 +216,85 @@ func findLODLayers(allLayers map[string]*mvt.Layer, settingsPtr *[]layerSetting,
 	return lodLayers
 }
 
-func fillContourLayers(lodLayers mvt.Layers, contours *mvt.Layer) {
-	pattern, _ := regexp.Compile("^contours/\\d+$")
-
+// fillContourLayers fills all contour layers of lodLayers with the lines of the contours layer
+// matching their contour interval and flags their index contours
+func fillContourLayers(lodLayers mvt.Layers, contours *mvt.Layer, settingsPtr *[]layerSetting) {
 	for index, layer := range lodLayers {
 
-		if !pattern.MatchString(layer.Name) {
+		var setting *layerSetting
+		for i := range *settingsPtr {
+			if (*settingsPtr)[i].Layer == layer.Name && (*settingsPtr)[i].isContourLayer() {
+				setting = &(*settingsPtr)[i]
+				break
+			}
+		}
+		if setting == nil {
 			continue
 		}
 
-		interval, err := strconv.Atoi(layer.Name[len("contours/"):])
-		if err != nil {
-			continue
+		intervalFeatures := make([]*geojson.Feature, 0)
+
+		for _, f := range contours.Features {
+			elev := f.Properties["dem_elevation"].(float64)
+
+			if !isMultipleOf(elev, *setting.ContourInterval) {
+				continue
+			}
+
+			// features are shared between the contour layers, but the index flag is not
+			feature := geojson.NewFeature(f.Geometry)
+			feature.ID = f.ID
+			feature.Properties = f.Properties.Clone()
+			feature.Properties["index"] = setting.IndexInterval != nil && isMultipleOf(elev, *setting.IndexInterval)
+
+			intervalFeatures = append(intervalFeatures, feature)
 		}
 
-		newLayer := mvt.Layer{
+		lodLayers[index] = &mvt.Layer{
 			Name:     layer.Name,
 			Version:  layer.Version,
 			Extent:   layer.Extent,
-			Features: contours.Features,
+			Features: intervalFeatures,
+		}
+	}
+}
+
+// fillContourLabels only keeps the labels of the index contours of the contour layers in lodLayers
+func fillContourLabels(lodLayers mvt.Layers, settingsPtr *[]layerSetting) {
+
+	// find index intervals of all contour layers of this LOD
+	indexIntervals := []float64{}
+	for _, layer := range lodLayers {
+		for _, setting := range *settingsPtr {
+			if setting.Layer == layer.Name && setting.isContourLayer() && setting.IndexInterval != nil {
+				indexIntervals = append(indexIntervals, *setting.IndexInterval)
+			}
 		}
+	}
 
-		if interval == 1 {
-			newLayer.Features = contours.Features
-		} else {
-			intervalFeatures := make([]*geojson.Feature, 0)
+	for index, layer := range lodLayers {
+		if layer.Name != "contour_labels" {
+			continue
+		}
 
-			for _, f := range contours.Features {
-				elev := f.Properties["dem_elevation"].(int)
+		labels := make([]*geojson.Feature, 0)
+		for _, f := range layer.Features {
+			elev := f.Properties["dem_elevation"].(float64)
 
-				if elev%interval == 0 {
-					intervalFeatures = append(intervalFeatures, f)
+			for _, interval := range indexIntervals {
+				if isMultipleOf(elev, interval) {
+					labels = append(labels, f)
+					break
 				}
 			}
+		}
 
-			newLayer.Features = intervalFeatures
-
+		lodLayers[index] = &mvt.Layer{
+			Name:     layer.Name,
+			Version:  layer.Version,
+			Extent:   layer.Extent,
+			Features: labels,
 		}
-		lodLayers[index] = &newLayer
 	}
 }
 
@@ -340,25 +361,6 @@ func createTile(x uint32, y uint32, layers mvt.Layers) ([]byte, error) {
 	return data, nil
 }
 
-func writeTile(tilePath string, data []byte) error {
-	f, err := os.Create(tilePath)
-	if err != nil {
-		return err
-	}
-
-	_, err = f.Write(data)
-	if err != nil {
-		return err
-	}
-
-	err = f.Close()
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 // projectLayersInPlace projects all features of a layer
 func projectLayersInPlace(layers map[string]*mvt.Layer, projection orb.Projection) {
 	for _, layer := range layers {
@@ -2,9 +2,11 @@ package mvt
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"sort"
 )
 
 const defaultLayerSettings = `
@@ -60,17 +62,84 @@ const defaultLayerSettings = `
     { "layer": "forest", "minzoom": 3 },
     { "layer": "rocks", "minzoom": 3 },
     { "layer": "mount", "minzoom": 2 },
-    { "layer": "contours/01", "minzoom": 8 },
-    { "layer": "contours/05", "minzoom": 7, "maxzoom": 7 },
-    { "layer": "contours/10", "minzoom": 5, "maxzoom": 6 },
-    { "layer": "contours/50", "minzoom": 3, "maxzoom": 4 },
-    { "layer": "contours/100", "minzoom": 0, "maxzoom": 2 }
+    { "layer": "contours/01", "minzoom": 8, "contour_interval": 1, "index_interval": 5 },
+    { "layer": "contours/05", "minzoom": 7, "maxzoom": 7, "contour_interval": 5, "index_interval": 25 },
+    { "layer": "contours/10", "minzoom": 5, "maxzoom": 6, "contour_interval": 10, "index_interval": 50 },
+    { "layer": "contours/50", "minzoom": 3, "maxzoom": 4, "contour_interval": 50, "index_interval": 250 },
+    { "layer": "contours/100", "minzoom": 0, "maxzoom": 2, "contour_interval": 100, "index_interval": 500 },
+    { "layer": "contour_labels", "minzoom": 3 },
+    { "layer": "elevation_bands", "minzoom": 0 },
+    { "layer": "bathymetry", "minzoom": 0 }
 ]`
 
 type layerSetting struct {
 	Layer   string `json:"layer"`
 	MinZoom *uint8 `json:"minzoom,omitempty"`
 	MaxZoom *uint8 `json:"maxzoom,omitempty"`
+
+	// layers with a contour interval are filled with the contour lines at every multiple
+	// of the interval (in meters, DEM elevation). Lines at multiples of the index interval
+	// are flagged as index contours.
+	ContourInterval *float64 `json:"contour_interval,omitempty"`
+	IndexInterval   *float64 `json:"index_interval,omitempty"`
+
+	// the elevation_bands and bathymetry layers are filled with polygons between each two
+	// consecutive breaks (in meters, DEM elevation for elevation_bands and depth below sea
+	// level for bathymetry). Both layers are only built if they have breaks.
+	Breaks []float64 `json:"breaks,omitempty"`
+
+	// the bathymetry layer additionally contains depth contours at every multiple of the
+	// depth interval (in meters), which also builds it without breaks
+	DepthInterval *float64 `json:"depth_interval,omitempty"`
+}
+
+// legacyContourIntervals are the contour and index intervals of the contour layers, which had
+// fixed intervals before they could be set in the layer settings
+var legacyContourIntervals = map[string][2]float64{
+	"contours/01":  {1, 5},
+	"contours/05":  {5, 25},
+	"contours/10":  {10, 50},
+	"contours/50":  {50, 250},
+	"contours/100": {100, 500},
+}
+
+// isContourLayer checks whether the layer is filled with contour lines
+func (s layerSetting) isContourLayer() bool {
+	return s.ContourInterval != nil
+}
+
+// contourLayerSettings returns the settings of all contour layers
+func contourLayerSettings(settings []layerSetting) []layerSetting {
+	contourSettings := []layerSetting{}
+
+	for _, setting := range settings {
+		if setting.isContourLayer() {
+			contourSettings = append(contourSettings, setting)
+		}
+	}
+
+	return contourSettings
+}
+
+// findLayerSetting returns the settings of a layer
+func findLayerSetting(settings []layerSetting, layerName string) (layerSetting, bool) {
+	for _, setting := range settings {
+		if setting.Layer == layerName {
+			return setting, true
+		}
+	}
+
+	return layerSetting{}, false
+}
+
+// layerBreaks returns the sorted breaks of a layer
+func layerBreaks(settings []layerSetting, layerName string) []float64 {
+	setting, _ := findLayerSetting(settings, layerName)
+
+	breaks := append([]float64{}, setting.Breaks...)
+	sort.Float64s(breaks)
+
+	return breaks
 }
 
 func loadLayerSettings(filePath string) []layerSetting {
@@ -92,12 +161,45 @@ func loadLayerSettings(filePath string) []layerSetting {
 		defer jsonFile.Close()
 
 		// read our opened jsonFile as a byte array.
-		byteValue, _ = ioutil.ReadAll(jsonFile)
+		byteValue, err = ioutil.ReadAll(jsonFile)
+		if err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	// we unmarshal our byteArray which contains our
 	// jsonFile's content into 'val'
-	json.Unmarshal(byteValue, &val)
+	err := json.Unmarshal(byteValue, &val)
+	if err != nil {
+		log.Fatal(fmt.Errorf("invalid layer settings: %w", err))
+	}
+
+	// layer settings, which were written before the intervals could be set, still get
+	// the contour lines of the legacy contour layers
+	for i, setting := range val {
+		intervals, isLegacy := legacyContourIntervals[setting.Layer]
+		if !isLegacy || setting.ContourInterval != nil {
+			continue
+		}
+
+		contourInterval, indexInterval := intervals[0], intervals[1]
+		val[i].ContourInterval = &contourInterval
+		if setting.IndexInterval == nil {
+			val[i].IndexInterval = &indexInterval
+		}
+	}
+
+	for _, setting := range val {
+		if setting.ContourInterval != nil && *setting.ContourInterval <= 0 {
+			log.Fatal(fmt.Errorf("contour_interval of layer %s must be greater than 0", setting.Layer))
+		}
+		if setting.IndexInterval != nil && *setting.IndexInterval <= 0 {
+			log.Fatal(fmt.Errorf("index_interval of layer %s must be greater than 0", setting.Layer))
+		}
+		if setting.DepthInterval != nil && *setting.DepthInterval <= 0 {
+			log.Fatal(fmt.Errorf("depth_interval of layer %s must be greater than 0", setting.Layer))
+		}
+	}
 
 	return val
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/gruppe-adler/meh-utils/internal/dem"
 	"github.com/gruppe-adler/meh-utils/internal/metajson"
 	"github.com/gruppe-adler/meh-utils/internal/tilejson"
+	"github.com/gruppe-adler/meh-utils/internal/tilewriter"
 	"github.com/gruppe-adler/meh-utils/internal/utils"
 	"github.com/gruppe-adler/meh-utils/internal/validate"
 	geojson "github.com/paulmach/orb/geojson"
@@ -26,9 +27,13 @@ func Run(flagSet *flag.FlagSet) {
 	var timer time.Time
 	start := time.Now()
 
-	outputPtr := flagSet.String("out", "", "Path to output directory")
+	outputPtr := flagSet.String("out", "", "Path to output directory (or output file for -format mbtiles / pmtiles)")
 	inputPtr := flagSet.String("in", "", "Path to grad_meh map directory")
+	formatPtr := flagSet.String("format", tilewriter.Directory, fmt.Sprintf("Output format (%s)", strings.Join(tilewriter.Formats, ", ")))
 	layerSettingsPtr := flagSet.String("layer_settings", "", "Path to layer_settings.json file")
+	demPtr := flagSet.String("dem", "", "Path to DEM (ESRI ASCII grid, XYZ, GeoTIFF or ESRI float grid), defaults to the dem.asc.gz of the grad_meh map directory")
+	smoothingPtr := flagSet.String("contour_smoothing", smoothingNone, fmt.Sprintf("Contour line smoothing (%s)", strings.Join(smoothingMethods, ", ")))
+	smoothingPassesPtr := flagSet.Uint("contour_smoothing_passes", 2, "Number of contour line smoothing passes (each pass doubles the number of points)")
 
 	flagSet.Parse(os.Args[2:])
 
@@ -38,18 +43,29 @@ func Run(flagSet *flag.FlagSet) {
 		os.Exit(1)
 	}
 
+	demPath := *demPtr
+	if demPath == "" {
+		demPath = path.Join(*inputPtr, "dem.asc.gz")
+	}
+
+	// make sure contour smoothing method is valid
+	if *smoothingPtr != smoothingNone && *smoothingPtr != smoothingChaikin && *smoothingPtr != smoothingSpline {
+		log.Fatal(fmt.Errorf("Unknown contour smoothing method: %s", *smoothingPtr))
+	}
+
 	// make sure layerSettings is either "" or a valid file
 	if *layerSettingsPtr != "" && !utils.IsFile(*layerSettingsPtr) {
 		log.Fatal(errors.New("LayerSettings is not a valid file"))
 	}
 
-	// make sure given output directory is a valid directory
-	if !utils.IsDirectory(*outputPtr) {
-		log.Fatal(errors.New("Output directory doesn't exists"))
+	// create tile writer (makes sure the output path is valid)
+	writer, err := tilewriter.New(*formatPtr, *outputPtr, "pbf")
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	// validate input directory structure
-	err := validate.MehDirectory(*inputPtr)
+	err = validate.MehDirectoryWithDEM(*inputPtr, demPath)
 	if err != nil {
 		log.Fatal(errors.New("Input directory doesn't exsist or doesn't have correct structre"))
 	}
@@ -73,13 +89,13 @@ func Run(flagSet *flag.FlagSet) {
 	// load DEM
 	timer = time.Now()
 	fmt.Println("▶️  Loading DEM")
-	raster := dem.Read(path.Join(*inputPtr, "dem.asc.gz"))
+	raster := dem.Read(demPath)
 	fmt.Println("✔️  Loaded DEM in", time.Now().Sub(timer).String())
 
 	// contour lines
 	timer = time.Now()
 	fmt.Println("▶️  Building contour lines")
-	buildContours(&raster, meta.ElevationOffset, meta.WorldSize, &collections)
+	buildContours(&raster, meta.ElevationOffset, meta.WorldSize, layerSettings, contourSmoothing{*smoothingPtr, *smoothingPassesPtr}, &collections)
 	fmt.Println("✔️  Built contour lines in", time.Now().Sub(timer).String())
 
 	// build mounts
@@ -113,14 +129,25 @@ func Run(flagSet *flag.FlagSet) {
 	// build mvts
 	timer = time.Now()
 	fmt.Println("▶️  Building mapbox vector tiles")
-	buildVectorTiles(*outputPtr, &collections, maxLod, meta.WorldSize, &layerSettings)
+	buildVectorTiles(writer, &collections, maxLod, meta.WorldSize, &layerSettings)
 	fmt.Println("✔️  Built mapbox vector tiles in", time.Now().Sub(timer).String())
 
-	// write tile.json
+	// write tile.json / metadata
 	timer = time.Now()
-	fmt.Println("▶️  Creating tile.json")
-	tilejson.Write(*outputPtr, maxLod, meta, "Mapbox Vector", layerNames)
-	fmt.Println("✔️  Created tile.json in", time.Now().Sub(timer).String())
+	fmt.Println("▶️  Writing tileset metadata")
+	tileJSON := tilejson.New(maxLod, meta, "Mapbox Vector", layerNames)
+	for i, vectorLayer := range tileJSON.VectorLayers {
+		for _, setting := range contourLayerSettings(layerSettings) {
+			if setting.Layer == vectorLayer.ID {
+				tileJSON.VectorLayers[i].Fields = tilejson.ContourLayerFields
+			}
+		}
+	}
+	err = writer.Close(tileJSON)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("✔️  Wrote tileset metadata in", time.Now().Sub(timer).String())
 
 	fmt.Printf("\n    🎉  Finished in %s\n", time.Now().Sub(start).String())
 }
@@ -0,0 +1,413 @@
+package mvt
+
+import (
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// contour line smoothing methods
+const (
+	smoothingNone    = "none"
+	smoothingChaikin = "chaikin"
+	smoothingSpline  = "spline"
+)
+
+var smoothingMethods = []string{smoothingNone, smoothingChaikin, smoothingSpline}
+
+// number of rounds in which smoothing is undone around crossings, before crossing lines aren't smoothed at all
+const maxSmoothingRounds = 8
+
+type contourSmoothing struct {
+	Method string
+	Passes uint
+}
+
+type smoothedPoint struct {
+	Point orb.Point
+	Src   int  // index of the vertex of the original line the point derives from
+	Fixed bool // fixed points are neither moved nor cut off
+}
+
+type segmentRef struct {
+	Line  int
+	Index int
+}
+
+// smoothContours smooths the lines of all contour features in place. Smoothing can make lines
+// which are close to each other cross, so the original vertices around every crossing are
+// kept and the lines are smoothed again, until no smoothed line crosses another one.
+func smoothContours(features []*geojson.Feature, smoothing contourSmoothing) {
+	if smoothing.Method == smoothingNone || smoothing.Passes == 0 {
+		return
+	}
+
+	originals := make([]orb.LineString, len(features))
+	frozen := make([][]bool, len(features))
+	smoothed := make([][]smoothedPoint, len(features))
+	dirty := []int{}
+
+	for i, f := range features {
+		line, ok := f.Geometry.(orb.LineString)
+		if !ok {
+			continue
+		}
+
+		// lines which are too short to be smoothed still can be crossed by smoothed lines
+		if len(line) < 3 {
+			smoothed[i] = make([]smoothedPoint, len(line))
+			for j, p := range line {
+				smoothed[i][j] = smoothedPoint{Point: p, Src: j, Fixed: true}
+			}
+			continue
+		}
+
+		originals[i] = line
+		frozen[i] = make([]bool, len(line))
+		dirty = append(dirty, i)
+	}
+
+	for round := 0; len(dirty) > 0; round++ {
+		smoothLines(dirty, originals, frozen, smoothed, smoothing)
+
+		crossings := findCrossings(smoothed, dirty)
+
+		// freeze the original vertices around all crossings, lines which still cross
+		// after a few rounds are frozen completely
+		dirty = []int{}
+		isDirty := make(map[int]bool)
+		for _, ref := range crossings {
+			line := ref.Line
+			points := smoothed[line]
+
+			// short lines aren't smoothed, so the line crossing them has to give way
+			if frozen[line] == nil {
+				continue
+			}
+
+			// the closing point of a ring is the same vertex as its first point
+			vertices := len(frozen[line])
+			if isClosed(originals[line]) {
+				vertices--
+			}
+
+			from := points[ref.Index].Src - 1
+			to := points[ref.Index+1].Src + 1
+			if from > to {
+				// segment closes a ring
+				to += vertices
+			}
+			if round >= maxSmoothingRounds {
+				from, to = 0, vertices-1
+			}
+
+			changed := false
+			for src := from; src <= to; src++ {
+				i := (src + vertices) % vertices
+				if !frozen[line][i] {
+					frozen[line][i] = true
+					changed = true
+				}
+			}
+
+			if changed && !isDirty[line] {
+				isDirty[line] = true
+				dirty = append(dirty, line)
+			}
+		}
+	}
+
+	for i, points := range smoothed {
+		if points == nil {
+			continue
+		}
+
+		line := make(orb.LineString, len(points))
+		for j, p := range points {
+			line[j] = p.Point
+		}
+		features[i].Geometry = line
+	}
+}
+
+// smoothLines smooths the given lines concurrently, starting from the original lines
+func smoothLines(indices []int, originals []orb.LineString, frozen [][]bool, smoothed [][]smoothedPoint, smoothing contourSmoothing) {
+	waitGrp := sync.WaitGroup{}
+	workers := runtime.NumCPU()
+
+	for w := 0; w < workers; w++ {
+		waitGrp.Add(1)
+		go func(w int) {
+			defer waitGrp.Done()
+
+			for i := w; i < len(indices); i += workers {
+				index := indices[i]
+				smoothed[index] = smoothLine(originals[index], frozen[index], smoothing)
+			}
+		}(w)
+	}
+
+	waitGrp.Wait()
+}
+
+// smoothLine smooths a single line. The end points of open lines are always fixed, because
+// they are at the edge of the raster.
+func smoothLine(line orb.LineString, frozen []bool, smoothing contourSmoothing) []smoothedPoint {
+	closed := isClosed(line)
+
+	points := make([]smoothedPoint, len(line))
+	for i, p := range line {
+		points[i] = smoothedPoint{Point: p, Src: i, Fixed: frozen[i]}
+	}
+
+	if closed {
+		// the duplicate closing point is added again afterwards
+		points = points[:len(points)-1]
+	} else {
+		points[0].Fixed = true
+		points[len(points)-1].Fixed = true
+	}
+
+	for pass := uint(0); pass < smoothing.Passes; pass++ {
+		switch smoothing.Method {
+		case smoothingChaikin:
+			points = chaikin(points, closed)
+		case smoothingSpline:
+			points = fourPoint(points, closed)
+		}
+	}
+
+	if closed {
+		points = append(points, points[0])
+	}
+
+	return points
+}
+
+// chaikin does one pass of Chaikin's corner cutting. Every vertex is replaced by two points
+// a quarter of the way towards its neighbours.
+func chaikin(points []smoothedPoint, closed bool) []smoothedPoint {
+	n := len(points)
+	result := make([]smoothedPoint, 0, 2*n)
+
+	for i, p := range points {
+		if p.Fixed || (!closed && (i == 0 || i == n-1)) {
+			result = append(result, p)
+			continue
+		}
+
+		prev := points[(i-1+n)%n].Point
+		next := points[(i+1)%n].Point
+
+		result = append(result,
+			smoothedPoint{Point: lerp(p.Point, prev, 0.25), Src: p.Src},
+			smoothedPoint{Point: lerp(p.Point, next, 0.25), Src: p.Src},
+		)
+	}
+
+	return result
+}
+
+// fourPoint does one pass of the interpolating four-point subdivision scheme. A point is
+// inserted between every two vertices, which converges to a smooth spline through the vertices.
+func fourPoint(points []smoothedPoint, closed bool) []smoothedPoint {
+	n := len(points)
+	result := make([]smoothedPoint, 0, 2*n)
+
+	segments := n - 1
+	if closed {
+		segments = n
+	}
+
+	for i := 0; i < segments; i++ {
+		a := points[i]
+		b := points[(i+1)%n]
+
+		mid := lerp(a.Point, b.Point, 0.5)
+
+		// segments next to fixed points stay straight
+		hasNeighbours := closed || (i > 0 && i+2 < n)
+		if !a.Fixed && !b.Fixed && hasNeighbours {
+			p0 := points[(i-1+n)%n].Point
+			p3 := points[(i+2)%n].Point
+
+			mid = orb.Point{
+				9.0/16.0*(a.Point[0]+b.Point[0]) - 1.0/16.0*(p0[0]+p3[0]),
+				9.0/16.0*(a.Point[1]+b.Point[1]) - 1.0/16.0*(p0[1]+p3[1]),
+			}
+		}
+
+		result = append(result, a, smoothedPoint{Point: mid, Src: a.Src})
+	}
+
+	if !closed {
+		result = append(result, points[n-1])
+	}
+
+	return result
+}
+
+// findCrossings finds all segments of the changed lines which cross another segment, and the
+// segments they cross. The segments are sorted into buckets of a grid, so only segments which
+// are close to each other are compared.
+func findCrossings(lines [][]smoothedPoint, changed []int) []segmentRef {
+	isChanged := make([]bool, len(lines))
+	for _, line := range changed {
+		isChanged[line] = true
+	}
+
+	// buckets are twice as big as the average segment
+	length := float64(0)
+	count := 0
+	for _, points := range lines {
+		for i := 1; i < len(points); i++ {
+			length += distance(points[i-1].Point, points[i].Point)
+			count++
+		}
+	}
+	if count == 0 || length == 0 {
+		return []segmentRef{}
+	}
+	bucketSize := 2 * length / float64(count)
+
+	// only buckets with a segment of a changed line can contain new crossings, so
+	// segments of the other lines are only added to existing buckets
+	buckets := make(map[[2]int][]segmentRef)
+	changedBound := orb.Bound{Min: orb.Point{math.Inf(1), math.Inf(1)}, Max: orb.Point{math.Inf(-1), math.Inf(-1)}}
+	for _, lineIndex := range changed {
+		points := lines[lineIndex]
+		for i := 1; i < len(points); i++ {
+			changedBound = changedBound.Extend(points[i].Point)
+			forEachBucket(points[i-1].Point, points[i].Point, bucketSize, func(key [2]int) {
+				buckets[key] = append(buckets[key], segmentRef{lineIndex, i - 1})
+			})
+		}
+	}
+	for lineIndex, points := range lines {
+		if isChanged[lineIndex] || len(points) == 0 || !lineBound(points).Intersects(changedBound) {
+			continue
+		}
+
+		for i := 1; i < len(points); i++ {
+			forEachBucket(points[i-1].Point, points[i].Point, bucketSize, func(key [2]int) {
+				if bucket, found := buckets[key]; found {
+					buckets[key] = append(bucket, segmentRef{lineIndex, i - 1})
+				}
+			})
+		}
+	}
+
+	bucketList := make([][]segmentRef, 0, len(buckets))
+	for _, bucket := range buckets {
+		if len(bucket) > 1 {
+			bucketList = append(bucketList, bucket)
+		}
+	}
+
+	crossings := []segmentRef{}
+	crossingsMux := sync.Mutex{}
+	waitGrp := sync.WaitGroup{}
+	workers := runtime.NumCPU()
+
+	for w := 0; w < workers; w++ {
+		waitGrp.Add(1)
+		go func(w int) {
+			defer waitGrp.Done()
+
+			found := []segmentRef{}
+			for k := w; k < len(bucketList); k += workers {
+				bucket := bucketList[k]
+
+				for i := 0; i < len(bucket); i++ {
+					for j := i + 1; j < len(bucket); j++ {
+						a, b := bucket[i], bucket[j]
+
+						if !isChanged[a.Line] && !isChanged[b.Line] {
+							continue
+						}
+
+						if a.Line == b.Line && adjacentSegments(len(lines[a.Line]), a.Index, b.Index) {
+							continue
+						}
+
+						pa := lines[a.Line]
+						pb := lines[b.Line]
+						if segmentsCross(pa[a.Index].Point, pa[a.Index+1].Point, pb[b.Index].Point, pb[b.Index+1].Point) {
+							found = append(found, a, b)
+						}
+					}
+				}
+			}
+
+			crossingsMux.Lock()
+			crossings = append(crossings, found...)
+			crossingsMux.Unlock()
+		}(w)
+	}
+
+	waitGrp.Wait()
+
+	return crossings
+}
+
+// forEachBucket calls fn for every bucket the bounding box of the segment a-b touches
+func forEachBucket(a, b orb.Point, bucketSize float64, fn func(key [2]int)) {
+	minX := int(math.Floor(math.Min(a[0], b[0]) / bucketSize))
+	maxX := int(math.Floor(math.Max(a[0], b[0]) / bucketSize))
+	minY := int(math.Floor(math.Min(a[1], b[1]) / bucketSize))
+	maxY := int(math.Floor(math.Max(a[1], b[1]) / bucketSize))
+
+	for bx := minX; bx <= maxX; bx++ {
+		for by := minY; by <= maxY; by++ {
+			fn([2]int{bx, by})
+		}
+	}
+}
+
+func lineBound(points []smoothedPoint) orb.Bound {
+	bound := orb.Bound{Min: points[0].Point, Max: points[0].Point}
+	for _, p := range points[1:] {
+		bound = bound.Extend(p.Point)
+	}
+	return bound
+}
+
+// adjacentSegments checks whether two segments of a line with n points share a point
+func adjacentSegments(n, i, j int) bool {
+	if i > j {
+		i, j = j, i
+	}
+
+	// the first and the last segment of a ring share the closing point
+	return j-i <= 1 || (i == 0 && j == n-2)
+}
+
+// segmentsCross checks whether the segments a1-a2 and b1-b2 properly cross each other
+func segmentsCross(a1, a2, b1, b2 orb.Point) bool {
+	d1 := orientation(b1, b2, a1)
+	d2 := orientation(b1, b2, a2)
+	d3 := orientation(a1, a2, b1)
+	d4 := orientation(a1, a2, b2)
+
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
+
+// orientation is positive if p is left of the line a-b, negative if it is right of it
+func orientation(a, b, p orb.Point) float64 {
+	return (b[0]-a[0])*(p[1]-a[1]) - (b[1]-a[1])*(p[0]-a[0])
+}
+
+func isClosed(line orb.LineString) bool {
+	return line[0] == line[len(line)-1]
+}
+
+func lerp(a, b orb.Point, t float64) orb.Point {
+	return orb.Point{a[0] + (b[0]-a[0])*t, a[1] + (b[1]-a[1])*t}
+}
+
+func distance(a, b orb.Point) float64 {
+	return math.Hypot(b[0]-a[0], b[1]-a[1])
+}
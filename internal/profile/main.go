@@ -0,0 +1,148 @@
+package profile
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+	"github.com/gruppe-adler/meh-utils/internal/metajson"
+	"github.com/gruppe-adler/meh-utils/internal/utils"
+	"github.com/gruppe-adler/meh-utils/internal/validate"
+	"github.com/paulmach/orb"
+)
+
+// Run is the program's entrypoint. The profile is written to stdout (unless -out is set) and
+// the summary to stderr, so that the output can be piped into other tools.
+func Run(flagSet *flag.FlagSet) {
+
+	inputPtr := flagSet.String("in", "", "Path to grad_meh map directory")
+	demPtr := flagSet.String("dem", "", "Path to DEM (ESRI ASCII grid, XYZ, GeoTIFF or ESRI float grid), defaults to the dem.asc.gz of the grad_meh map directory")
+	pathPtr := flagSet.String("path", "", "Path to file with one point (x,y) of the path per line. Points can also be passed as arguments.")
+	roadLayerPtr := flagSet.String("road_layer", "", "Use a road from this roads/* GeoJSON layer as path (e.g. main_road)")
+	roadIndexPtr := flagSet.Int("road_index", 0, "Index of the road in -road_layer")
+	intervalPtr := flagSet.Float64("interval", 10, "Distance between the samples in meters")
+	outputPtr := flagSet.String("out", "", "Path to output file (stdout if empty)")
+	outputFormatPtr := flagSet.String("out_format", CSV, fmt.Sprintf("Output format (%s, %s)", CSV, JSON))
+	svgPtr := flagSet.String("svg", "", "Path to output SVG file with a chart of the profile (optional)")
+
+	flagSet.Parse(os.Args[2:])
+
+	if *inputPtr == "" {
+		flagSet.PrintDefaults()
+		os.Exit(1)
+	}
+
+	if *outputFormatPtr != CSV && *outputFormatPtr != JSON {
+		log.Fatal(fmt.Errorf("Unknown output format: %s", *outputFormatPtr))
+	}
+
+	if *intervalPtr <= 0 {
+		log.Fatal(errors.New("Interval must be greater than 0"))
+	}
+
+	demPath := *demPtr
+	if demPath == "" {
+		demPath = path.Join(*inputPtr, "dem.asc.gz")
+	}
+
+	// validate input directory structure
+	err := validate.MehDirectoryWithDEM(*inputPtr, demPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// read path
+	line, err := readPath(flagSet.Args(), *pathPtr, path.Join(*inputPtr, "geojson"), *roadLayerPtr, *roadIndexPtr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(line) < 2 {
+		log.Fatal(errors.New("The path needs at least two points"))
+	}
+
+	meta, err := metajson.Read(path.Join(*inputPtr, "meta.json"))
+	if err != nil {
+		log.Fatal(errors.New("Failed to read meta.json"))
+	}
+
+	sampler := dem.Sampler{
+		Raster:          dem.Read(demPath),
+		ElevationOffset: meta.ElevationOffset,
+		Interpolation:   dem.Bilinear,
+	}
+
+	profile := sampler.Profile(line, *intervalPtr)
+
+	// write profile
+	var out io.Writer = os.Stdout
+	if *outputPtr != "" {
+		file, err := os.Create(*outputPtr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if *outputFormatPtr == JSON {
+		err = writeJSON(out, profile)
+	} else {
+		err = writeCSV(out, profile)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// write chart
+	if *svgPtr != "" {
+		file, err := os.Create(*svgPtr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = writeSVG(file, profile)
+		file.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "ℹ️ ", summary(profile))
+}
+
+// readPath reads the path from a road layer, a file or the arguments
+func readPath(args []string, pathFile string, geoJSONDir string, roadLayer string, roadIndex int) (orb.LineString, error) {
+	if roadLayer != "" {
+		return readRoad(geoJSONDir, roadLayer, roadIndex)
+	}
+
+	line := orb.LineString{}
+
+	if pathFile != "" {
+		file, err := os.Open(pathFile)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		points, err := utils.ParseCoordinates(file)
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, points...)
+	}
+
+	for _, arg := range args {
+		p, err := utils.ParseCoordinate(arg)
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, p)
+	}
+
+	return line, nil
+}
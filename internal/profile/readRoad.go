@@ -0,0 +1,55 @@
+package profile
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+)
+
+// readRoad reads the path of the road with given index from a roads/* layer of the grad_meh
+// geojson directory. The parts of multi line strings are joined in their order.
+func readRoad(geoJSONDir string, layer string, index int) (orb.LineString, error) {
+	layer = strings.TrimPrefix(layer, "roads/")
+	filePath := path.Join(geoJSONDir, "roads", layer+".geojson.gz")
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var features []geojson.Feature
+	err = json.NewDecoder(gz).Decode(&features)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filePath, err)
+	}
+
+	if index < 0 || index >= len(features) {
+		return nil, fmt.Errorf("%s has %d roads, index %d is out of range", filePath, len(features), index)
+	}
+
+	switch g := features[index].Geometry.(type) {
+	case orb.LineString:
+		return g, nil
+	case orb.MultiLineString:
+		line := orb.LineString{}
+		for _, part := range g {
+			line = append(line, part...)
+		}
+		return line, nil
+	default:
+		return nil, fmt.Errorf("Road %d of %s is a %s and not a line", index, filePath, g.GeoJSONType())
+	}
+}
@@ -0,0 +1,83 @@
+package profile
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+)
+
+// dimensions of the chart in pixels
+const (
+	svgWidth   = 800
+	svgHeight  = 300
+	svgPadding = 50
+)
+
+// writeSVG renders the profile as an area chart with elevation over distance
+func writeSVG(w io.Writer, profile dem.Profile) error {
+	plotWidth := float64(svgWidth - 2*svgPadding)
+	plotHeight := float64(svgHeight - 2*svgPadding)
+
+	minElevation, maxElevation := profile.MinElevation, profile.MaxElevation
+	if maxElevation-minElevation < 1 {
+		maxElevation = minElevation + 1
+	}
+	length := math.Max(profile.Length, 1)
+
+	toX := func(distance float64) float64 {
+		return svgPadding + distance/length*plotWidth
+	}
+	toY := func(elevation float64) float64 {
+		return svgPadding + (maxElevation-elevation)/(maxElevation-minElevation)*plotHeight
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", svgWidth, svgHeight, svgWidth, svgHeight)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="white"/>`+"\n", svgWidth, svgHeight)
+
+	// the samples without data split the chart into several areas
+	areas := [][]dem.ProfilePoint{{}}
+	for _, p := range profile.Points {
+		if !p.HasData {
+			if len(areas[len(areas)-1]) > 0 {
+				areas = append(areas, []dem.ProfilePoint{})
+			}
+			continue
+		}
+		areas[len(areas)-1] = append(areas[len(areas)-1], p)
+	}
+
+	for _, area := range areas {
+		if len(area) == 0 {
+			continue
+		}
+
+		points := []string{fmt.Sprintf("%.1f,%.1f", toX(area[0].Distance), toY(minElevation))}
+		for _, p := range area {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", toX(p.Distance), toY(p.Elevation)))
+		}
+		points = append(points, fmt.Sprintf("%.1f,%.1f", toX(area[len(area)-1].Distance), toY(minElevation)))
+
+		fmt.Fprintf(&b, `<polygon points="%s" fill="#c8dcb4" stroke="#4a7a2c" stroke-width="1.5"/>`+"\n", strings.Join(points, " "))
+	}
+
+	// axes
+	fmt.Fprintf(&b, `<path d="M%d,%d V%d H%d" fill="none" stroke="black"/>`+"\n", svgPadding, svgPadding, svgHeight-svgPadding, svgWidth-svgPadding)
+
+	for i := 0; i <= 4; i++ {
+		elevation := minElevation + float64(i)/4*(maxElevation-minElevation)
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%.0f m</text>`+"\n", svgPadding-5, toY(elevation), elevation)
+
+		distance := float64(i) / 4 * profile.Length
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle">%.0f m</text>`+"\n", toX(distance), svgHeight-svgPadding+18, distance)
+	}
+
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle">%s</text>`+"\n", svgWidth/2, svgPadding/2, summary(profile))
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,63 @@
+package profile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+)
+
+// output formats of a profile
+const (
+	CSV  = "csv"
+	JSON = "json"
+)
+
+// writeCSV writes one row per sample with distance, x, y and elevation. Samples without data have an empty elevation.
+func writeCSV(w io.Writer, profile dem.Profile) error {
+	out := csv.NewWriter(w)
+
+	err := out.Write([]string{"distance", "x", "y", "elevation"})
+	if err != nil {
+		return err
+	}
+
+	format := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', 2, 64)
+	}
+
+	for _, p := range profile.Points {
+		elevation := ""
+		if p.HasData {
+			elevation = format(p.Elevation)
+		}
+
+		err = out.Write([]string{format(p.Distance), format(p.X), format(p.Y), elevation})
+		if err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// writeJSON writes the profile including its summary
+func writeJSON(w io.Writer, profile dem.Profile) error {
+	bytes, err := json.MarshalIndent(profile, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	return err
+}
+
+// summary is a human readable summary of the profile
+func summary(profile dem.Profile) string {
+	return fmt.Sprintf("length %.0fm, ascent %.0fm, descent %.0fm, elevation %.0fm - %.0fm",
+		profile.Length, profile.Ascent, profile.Descent, profile.MinElevation, profile.MaxElevation)
+}
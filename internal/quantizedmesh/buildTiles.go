@@ -0,0 +1,74 @@
+package quantizedmesh
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+)
+
+// buildTiles builds the quantized-mesh tiles of all LODs and returns the available tiles of each LOD
+func buildTiles(outputDirectory string, raster dem.EsriASCIIRaster, placement worldPlacement, elevationOffset float64, maxLod uint8, withNormals bool) [][]tileRange {
+	available := make([][]tileRange, maxLod+1)
+
+	for lod := uint8(0); lod <= maxLod; lod++ {
+		start := time.Now()
+
+		tiles := placement.tilesOfLod(lod)
+		available[lod] = []tileRange{tiles}
+
+		maxError := levelMaxGeometricError(lod)
+
+		tileWaitGroup := sync.WaitGroup{}
+		sem := semaphore.NewWeighted(int64(runtime.NumCPU()))
+
+		for col := tiles.StartX; col <= tiles.EndX; col++ {
+			dirPath := path.Join(outputDirectory, fmt.Sprintf("%d", lod), fmt.Sprintf("%d", col))
+			err := os.MkdirAll(dirPath, os.ModePerm)
+			if err != nil {
+				fmt.Printf("Error while creating directory %s\n", dirPath)
+				continue
+			}
+
+			for row := tiles.StartY; row <= tiles.EndY; row++ {
+				tileWaitGroup.Add(1)
+				go func(c, r uint32) {
+					defer tileWaitGroup.Done()
+
+					sem.Acquire(context.Background(), 1)
+					defer sem.Release(1)
+
+					bounds := geographicTileBounds(lod, c, r)
+					heights := tileHeights(raster, placement, elevationOffset, bounds)
+
+					positions := gridPositions(heights, bounds)
+
+					data, err := encodeTile(triangulate(positions, maxError), heights, positions, withNormals)
+					if err != nil {
+						fmt.Printf("Error while creating tile %d/%d/%d\n", lod, c, r)
+						return
+					}
+
+					err = os.WriteFile(path.Join(dirPath, fmt.Sprintf("%d.terrain", r)), data, 0644)
+					if err != nil {
+						fmt.Printf("Error while writing tile %d/%d/%d\n", lod, c, r)
+						return
+					}
+				}(col, row)
+			}
+		}
+
+		tileWaitGroup.Wait()
+
+		fmt.Println("    ✔️  Finished tiles for LOD", lod, "in", time.Now().Sub(start).String())
+	}
+
+	return available
+}
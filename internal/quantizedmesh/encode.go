@@ -0,0 +1,305 @@
+package quantizedmesh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// WGS84 ellipsoid
+const (
+	ellipsoidA = 6378137.0
+	ellipsoidB = 6356752.3142451793
+)
+
+// extension IDs of the quantized-mesh format
+const extensionOctVertexNormals = 1
+
+// farAway is the magnitude (in ellipsoid radii) of the horizon occlusion point of tiles which are never occluded
+const farAway = 1e6
+
+// maxQuantized is the maximum of the quantized u, v and height values
+const maxQuantized = 32767
+
+type vec3 [3]float64
+
+func (a vec3) add(b vec3) vec3      { return vec3{a[0] + b[0], a[1] + b[1], a[2] + b[2]} }
+func (a vec3) sub(b vec3) vec3      { return vec3{a[0] - b[0], a[1] - b[1], a[2] - b[2]} }
+func (a vec3) scale(f float64) vec3 { return vec3{a[0] * f, a[1] * f, a[2] * f} }
+func (a vec3) dot(b vec3) float64   { return a[0]*b[0] + a[1]*b[1] + a[2]*b[2] }
+func (a vec3) length() float64      { return math.Sqrt(a.dot(a)) }
+func (a vec3) div(b vec3) vec3      { return vec3{a[0] / b[0], a[1] / b[1], a[2] / b[2]} }
+func (a vec3) mul(b vec3) vec3      { return vec3{a[0] * b[0], a[1] * b[1], a[2] * b[2]} }
+func (a vec3) normalize() vec3      { return a.scale(1 / a.length()) }
+func (a vec3) cross(b vec3) vec3 {
+	return vec3{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+var ellipsoidRadii = vec3{ellipsoidA, ellipsoidA, ellipsoidB}
+
+// toECEF converts a geodetic position (degrees / meters) to earth-centered, earth-fixed coordinates
+func toECEF(lon, lat, height float64) vec3 {
+	lonRad := lon * math.Pi / 180
+	latRad := lat * math.Pi / 180
+
+	e2 := 1 - (ellipsoidB*ellipsoidB)/(ellipsoidA*ellipsoidA)
+	n := ellipsoidA / math.Sqrt(1-e2*math.Sin(latRad)*math.Sin(latRad))
+
+	return vec3{
+		(n + height) * math.Cos(latRad) * math.Cos(lonRad),
+		(n + height) * math.Cos(latRad) * math.Sin(lonRad),
+		(n*(1-e2) + height) * math.Sin(latRad),
+	}
+}
+
+// quantizedMeshHeader is the header of a quantized-mesh-1.0 tile
+// (see https://github.com/CesiumGS/quantized-mesh)
+type quantizedMeshHeader struct {
+	Center                vec3
+	MinimumHeight         float32
+	MaximumHeight         float32
+	BoundingSphereCenter  vec3
+	BoundingSphereRadius  float64
+	HorizonOcclusionPoint vec3
+}
+
+// encodeTile encodes the mesh of a tile. heights and positions are the heights and
+// earth-centered positions of the tile's grid.
+func encodeTile(m mesh, heights []float64, gridPositions []vec3, withNormals bool) ([]byte, error) {
+	minHeight := math.Inf(1)
+	maxHeight := math.Inf(-1)
+	for _, v := range m.vertices {
+		h := heights[v[1]*gridSize+v[0]]
+		minHeight = math.Min(minHeight, h)
+		maxHeight = math.Max(maxHeight, h)
+	}
+
+	// positions of all vertices
+	positions := make([]vec3, len(m.vertices))
+	for i, v := range m.vertices {
+		positions[i] = gridPositions[v[1]*gridSize+v[0]]
+	}
+
+	header := quantizedMeshHeader{
+		MinimumHeight: float32(minHeight),
+		MaximumHeight: float32(maxHeight),
+	}
+	header.BoundingSphereCenter, header.BoundingSphereRadius = boundingSphere(positions)
+	header.Center = header.BoundingSphereCenter
+	header.HorizonOcclusionPoint = horizonOcclusionPoint(positions, header.BoundingSphereCenter)
+
+	buf := new(bytes.Buffer)
+	err := binary.Write(buf, binary.LittleEndian, header)
+	if err != nil {
+		return nil, err
+	}
+
+	// vertex data
+	vertexCount := len(m.vertices)
+	us := make([]uint16, vertexCount)
+	vs := make([]uint16, vertexCount)
+	hs := make([]uint16, vertexCount)
+	for i, v := range m.vertices {
+		us[i] = uint16(math.Round(float64(v[0]) / (gridSize - 1) * maxQuantized))
+		vs[i] = uint16(math.Round(float64(gridSize-1-v[1]) / (gridSize - 1) * maxQuantized))
+		if maxHeight > minHeight {
+			hs[i] = uint16(math.Round((heights[v[1]*gridSize+v[0]] - minHeight) / (maxHeight - minHeight) * maxQuantized))
+		}
+	}
+
+	binary.Write(buf, binary.LittleEndian, uint32(vertexCount))
+	binary.Write(buf, binary.LittleEndian, zigZagDeltaEncode(us))
+	binary.Write(buf, binary.LittleEndian, zigZagDeltaEncode(vs))
+	binary.Write(buf, binary.LittleEndian, zigZagDeltaEncode(hs))
+
+	// index data (a tile has at most gridSize * gridSize vertices, so 16 bit indices are always sufficient)
+	indices := make([]uint16, 0, len(m.triangles)*3)
+	for _, t := range m.triangles {
+		// triangles of the RTIN are always counter-clockwise in u/v space
+		indices = append(indices, uint16(t[0]), uint16(t[1]), uint16(t[2]))
+	}
+
+	binary.Write(buf, binary.LittleEndian, uint32(len(m.triangles)))
+	binary.Write(buf, binary.LittleEndian, highWaterMarkEncode(indices))
+
+	// edge indices
+	west, south, east, north := edgeIndices(m)
+	for _, edge := range [][]uint16{west, south, east, north} {
+		binary.Write(buf, binary.LittleEndian, uint32(len(edge)))
+		binary.Write(buf, binary.LittleEndian, edge)
+	}
+
+	// extensions
+	if withNormals {
+		normals := vertexNormals(m, positions)
+
+		binary.Write(buf, binary.LittleEndian, uint8(extensionOctVertexNormals))
+		binary.Write(buf, binary.LittleEndian, uint32(len(normals)*2))
+		for _, n := range normals {
+			x, y := octEncode(n)
+			binary.Write(buf, binary.LittleEndian, []uint8{x, y})
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// zigZagDeltaEncode encodes every value as the zig-zag encoded difference to its predecessor
+func zigZagDeltaEncode(values []uint16) []uint16 {
+	encoded := make([]uint16, len(values))
+	prev := 0
+	for i, value := range values {
+		delta := int(value) - prev
+		encoded[i] = uint16((delta << 1) ^ (delta >> 31))
+		prev = int(value)
+	}
+	return encoded
+}
+
+// highWaterMarkEncode encodes the indices relative to the highest index so far.
+// This requires vertices to be numbered in order of their first use.
+func highWaterMarkEncode(indices []uint16) []uint16 {
+	encoded := make([]uint16, len(indices))
+	highest := uint16(0)
+	for i, index := range indices {
+		encoded[i] = highest - index
+		if index == highest {
+			highest++
+		}
+	}
+	return encoded
+}
+
+// edgeIndices returns the indices of the vertices on the west, south, east and north edge of the tile
+func edgeIndices(m mesh) (west, south, east, north []uint16) {
+	for i, v := range m.vertices {
+		if v[0] == 0 {
+			west = append(west, uint16(i))
+		}
+		if v[1] == gridSize-1 {
+			south = append(south, uint16(i))
+		}
+		if v[0] == gridSize-1 {
+			east = append(east, uint16(i))
+		}
+		if v[1] == 0 {
+			north = append(north, uint16(i))
+		}
+	}
+
+	sortBy := func(indices []uint16, coord func(v [2]int) int) {
+		sort.Slice(indices, func(i, j int) bool {
+			return coord(m.vertices[indices[i]]) < coord(m.vertices[indices[j]])
+		})
+	}
+	sortBy(west, func(v [2]int) int { return -v[1] })
+	sortBy(south, func(v [2]int) int { return v[0] })
+	sortBy(east, func(v [2]int) int { return -v[1] })
+	sortBy(north, func(v [2]int) int { return v[0] })
+
+	return west, south, east, north
+}
+
+// boundingSphere calculates a sphere around the center of the bounding box of all positions
+func boundingSphere(positions []vec3) (vec3, float64) {
+	min := positions[0]
+	max := positions[0]
+	for _, p := range positions {
+		for i := 0; i < 3; i++ {
+			min[i] = math.Min(min[i], p[i])
+			max[i] = math.Max(max[i], p[i])
+		}
+	}
+
+	center := min.add(max).scale(0.5)
+
+	radius := 0.0
+	for _, p := range positions {
+		radius = math.Max(radius, p.sub(center).length())
+	}
+
+	return center, radius
+}
+
+// horizonOcclusionPoint calculates the point in ellipsoid-scaled space, which is
+// below the horizon exactly when all positions are (see EllipsoidalOccluder in CesiumJS)
+func horizonOcclusionPoint(positions []vec3, directionToPoint vec3) vec3 {
+	scaledDirection := directionToPoint.div(ellipsoidRadii).normalize()
+
+	maxMagnitude := 0.0
+	for _, p := range positions {
+		scaled := p.div(ellipsoidRadii)
+		magnitudeSquared := scaled.dot(scaled)
+		magnitude := math.Sqrt(magnitudeSquared)
+		direction := scaled.scale(1 / magnitude)
+
+		// positions below the ellipsoid are handled as if they were on it
+		magnitudeSquared = math.Max(1, magnitudeSquared)
+		magnitude = math.Max(1, magnitude)
+
+		cosAlpha := direction.dot(scaledDirection)
+		sinAlpha := direction.cross(scaledDirection).length()
+		cosBeta := 1 / magnitude
+		sinBeta := math.Sqrt(magnitudeSquared-1) * cosBeta
+
+		denominator := cosAlpha*cosBeta - sinAlpha*sinBeta
+
+		// the position can't be occluded by the ellipsoid at all (happens for tiles
+		// spanning large parts of the globe), so we use a point far away instead
+		if denominator <= 0 {
+			return scaledDirection.scale(farAway)
+		}
+
+		maxMagnitude = math.Max(maxMagnitude, 1/denominator)
+	}
+
+	return scaledDirection.scale(maxMagnitude)
+}
+
+// vertexNormals calculates the normal of every vertex as the area-weighted average of the normals of its triangles
+func vertexNormals(m mesh, positions []vec3) []vec3 {
+	normals := make([]vec3, len(positions))
+
+	for _, t := range m.triangles {
+		a, b, c := positions[t[0]], positions[t[1]], positions[t[2]]
+		faceNormal := b.sub(a).cross(c.sub(a))
+		for _, i := range t {
+			normals[i] = normals[i].add(faceNormal)
+		}
+	}
+
+	for i, n := range normals {
+		if n.length() == 0 {
+			// fall back to the normal of the ellipsoid
+			n = positions[i].div(ellipsoidRadii.mul(ellipsoidRadii))
+		}
+		normals[i] = n.normalize()
+	}
+
+	return normals
+}
+
+// octEncode encodes an unit vector with the oct-encoding used by the octvertexnormals extension
+func octEncode(n vec3) (uint8, uint8) {
+	sum := math.Abs(n[0]) + math.Abs(n[1]) + math.Abs(n[2])
+	x := n[0] / sum
+	y := n[1] / sum
+
+	if n[2] < 0 {
+		x, y = (1-math.Abs(y))*signNotZero(x), (1-math.Abs(x))*signNotZero(y)
+	}
+
+	toSNorm := func(value float64) uint8 {
+		return uint8(math.Round((math.Max(-1, math.Min(1, value))*0.5 + 0.5) * 255))
+	}
+
+	return toSNorm(x), toSNorm(y)
+}
+
+func signNotZero(value float64) float64 {
+	if value < 0 {
+		return -1
+	}
+	return 1
+}
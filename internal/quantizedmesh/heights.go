@@ -0,0 +1,43 @@
+package quantizedmesh
+
+import (
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+)
+
+// tileHeights samples the heights of the grid of a tile. Everything outside of the world and
+// without data is at sea level.
+func tileHeights(raster dem.EsriASCIIRaster, placement worldPlacement, elevationOffset float64, bounds tileBounds) []float64 {
+	heights := make([]float64, gridSize*gridSize)
+
+	for y := 0; y < gridSize; y++ {
+		lat := bounds.north - float64(y)/(gridSize-1)*(bounds.north-bounds.south)
+
+		for x := 0; x < gridSize; x++ {
+			lon := bounds.west + float64(x)/(gridSize-1)*(bounds.east-bounds.west)
+
+			worldX, worldY := placement.toWorld(lon, lat)
+			height, ok := raster.Sample(worldX, worldY, dem.Bilinear)
+			if ok {
+				heights[y*gridSize+x] = height + elevationOffset
+			}
+		}
+	}
+
+	return heights
+}
+
+// gridPositions converts the heights of the grid of a tile to earth-centered positions
+func gridPositions(heights []float64, bounds tileBounds) []vec3 {
+	positions := make([]vec3, gridSize*gridSize)
+
+	for y := 0; y < gridSize; y++ {
+		lat := bounds.north - float64(y)/(gridSize-1)*(bounds.north-bounds.south)
+
+		for x := 0; x < gridSize; x++ {
+			lon := bounds.west + float64(x)/(gridSize-1)*(bounds.east-bounds.west)
+			positions[y*gridSize+x] = toECEF(lon, lat, heights[y*gridSize+x])
+		}
+	}
+
+	return positions
+}
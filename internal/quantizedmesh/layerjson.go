@@ -0,0 +1,62 @@
+package quantizedmesh
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/gruppe-adler/meh-utils/internal/metajson"
+)
+
+// layerJSON is the metadata of a quantized-mesh tileset, which is read by Cesium's CesiumTerrainProvider
+type layerJSON struct {
+	TileJSON    string        `json:"tilejson"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Version     string        `json:"version"`
+	Format      string        `json:"format"`
+	Attribution string        `json:"attribution,omitempty"`
+	Scheme      string        `json:"scheme"`
+	Tiles       []string      `json:"tiles"`
+	Extensions  []string      `json:"extensions"`
+	Projection  string        `json:"projection"`
+	Bounds      []float64     `json:"bounds"`
+	Minzoom     uint8         `json:"minzoom"`
+	Maxzoom     uint8         `json:"maxzoom"`
+	Available   [][]tileRange `json:"available"`
+}
+
+func newLayerJSON(meta metajson.MetaJSON, available [][]tileRange, withNormals bool) layerJSON {
+	extensions := []string{}
+	if withNormals {
+		extensions = append(extensions, "octvertexnormals")
+	}
+
+	return layerJSON{
+		TileJSON:    "2.1.0",
+		Name:        fmt.Sprintf("%s Terrain", meta.DisplayName),
+		Description: fmt.Sprintf("Quantized-mesh terrain of the Arma 3 Map '%s' from %s", meta.DisplayName, meta.Author),
+		Version:     "1.0.0",
+		Format:      "quantized-mesh-1.0",
+		Attribution: meta.Author,
+		Scheme:      "tms",
+		Tiles:       []string{"{z}/{x}/{y}.terrain?v={version}"},
+		Extensions:  extensions,
+		Projection:  "EPSG:4326",
+		Bounds:      []float64{-180, -90, 180, 90},
+		Minzoom:     0,
+		Maxzoom:     uint8(len(available) - 1),
+		Available:   available,
+	}
+}
+
+// writeLayerJSON writes the layer.json to the output directory
+func writeLayerJSON(outputDirectory string, obj layerJSON) error {
+	bytes, err := json.MarshalIndent(obj, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path.Join(outputDirectory, "layer.json"), bytes, 0644)
+}
@@ -0,0 +1,85 @@
+package quantizedmesh
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"time"
+
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+	"github.com/gruppe-adler/meh-utils/internal/metajson"
+	"github.com/gruppe-adler/meh-utils/internal/utils"
+	"github.com/gruppe-adler/meh-utils/internal/validate"
+)
+
+// Run is the program's entrypoint
+func Run(flagSet *flag.FlagSet) {
+
+	var timer time.Time
+	start := time.Now()
+
+	outputPtr := flagSet.String("out", "", "Path to output directory")
+	inputPtr := flagSet.String("in", "", "Path to grad_meh map directory")
+	normalsPtr := flagSet.Bool("normals", false, "Include per vertex normals (octvertexnormals extension) for terrain lighting")
+
+	flagSet.Parse(os.Args[2:])
+
+	// make sure both flags are present
+	if *outputPtr == "" || *inputPtr == "" {
+		flagSet.PrintDefaults()
+		os.Exit(1)
+	}
+
+	// make sure given output directory is a valid directory
+	if !utils.IsDirectory(*outputPtr) {
+		log.Fatal(errors.New("Output directory doesn't exists"))
+	}
+
+	// validate input directory structure
+	err := validate.MehDirectory(*inputPtr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("✔️  Validated input directory structure")
+
+	// load meta.json
+	timer = time.Now()
+	fmt.Println("▶️  Loading meta.json")
+	meta, err := metajson.Read(path.Join(*inputPtr, "meta.json"))
+	if err != nil {
+		log.Fatal(errors.New("Failed to read meta.json"))
+	}
+	fmt.Println("✔️  Loaded meta.json in", time.Now().Sub(timer).String())
+
+	// load DEM
+	timer = time.Now()
+	fmt.Println("▶️  Loading DEM")
+	raster := dem.Read(path.Join(*inputPtr, "dem.asc.gz"))
+	fmt.Println("✔️  Loaded DEM in", time.Now().Sub(timer).String())
+
+	// calculate max LOD
+	placement := newWorldPlacement(meta)
+	maxLod := calcMaxLod(raster.CellSize)
+	fmt.Println("ℹ️  Calculated max lod:", maxLod)
+
+	// build tiles
+	timer = time.Now()
+	fmt.Println("▶️  Building quantized-mesh tiles")
+	available := buildTiles(*outputPtr, raster, placement, meta.ElevationOffset, maxLod, *normalsPtr)
+	fmt.Println("✔️  Built quantized-mesh tiles in", time.Now().Sub(timer).String())
+
+	// write layer.json
+	timer = time.Now()
+	fmt.Println("▶️  Writing layer.json")
+	err = writeLayerJSON(*outputPtr, newLayerJSON(meta, available, *normalsPtr))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("✔️  Wrote layer.json in", time.Now().Sub(timer).String())
+
+	fmt.Printf("\n    🎉  Finished in %s\n", time.Now().Sub(start).String())
+}
@@ -0,0 +1,146 @@
+package quantizedmesh
+
+import (
+	"math"
+)
+
+// gridSize is the number of height samples per row / col of a tile (must be 2^n + 1)
+const gridSize = 65
+
+// rtinTriangle is a right triangle of the grid. A and B are the ends of the
+// hypotenuse and C is the vertex with the right angle.
+type rtinTriangle struct {
+	ax, ay, bx, by, cx, cy int
+}
+
+// splittable returns whether the triangle can be split into two smaller triangles
+func (t rtinTriangle) splittable() bool {
+	return absInt(t.ax-t.cx)+absInt(t.ay-t.cy) > 1
+}
+
+// children splits the triangle at the middle of its hypotenuse
+func (t rtinTriangle) children() (rtinTriangle, rtinTriangle) {
+	mx := (t.ax + t.bx) / 2
+	my := (t.ay + t.by) / 2
+
+	return rtinTriangle{t.cx, t.cy, t.ax, t.ay, mx, my}, rtinTriangle{t.bx, t.by, t.cx, t.cy, mx, my}
+}
+
+// rootTriangles are the two triangles covering the whole grid
+var rootTriangles = []rtinTriangle{
+	{0, 0, gridSize - 1, gridSize - 1, gridSize - 1, 0},
+	{gridSize - 1, gridSize - 1, 0, 0, 0, gridSize - 1},
+}
+
+// rtinLevels holds all splittable triangles of the grid grouped by their depth
+var rtinLevels = buildRTINLevels()
+
+func buildRTINLevels() [][]rtinTriangle {
+	levels := [][]rtinTriangle{rootTriangles}
+
+	for {
+		next := []rtinTriangle{}
+		for _, t := range levels[len(levels)-1] {
+			left, right := t.children()
+			if left.splittable() {
+				next = append(next, left)
+			}
+			if right.splittable() {
+				next = append(next, right)
+			}
+		}
+
+		if len(next) == 0 {
+			return levels
+		}
+
+		levels = append(levels, next)
+	}
+}
+
+// mesh is a triangulated tile. Vertices are grid positions (x from west to east, y from north to south).
+type mesh struct {
+	vertices  [][2]int
+	triangles [][3]int
+}
+
+// triangulate builds a right-triangulated irregular network (RTIN) of the earth-centered positions
+// of a gridSize x gridSize grid (row-major, starting in the north west), which differs at most
+// maxError meters from the positions (see https://www.cs.ubc.ca/~will/papers/rtin.pdf and
+// https://github.com/mapbox/martini). Measuring the error in 3D includes the curvature of the
+// earth, so large tiles are split even if they are flat.
+func triangulate(positions []vec3, maxError float64) mesh {
+	errors := calcErrors(positions)
+
+	m := mesh{}
+	indices := make(map[int]int)
+
+	vertexIndex := func(x, y int) int {
+		key := y*gridSize + x
+		index, found := indices[key]
+		if !found {
+			index = len(m.vertices)
+			indices[key] = index
+			m.vertices = append(m.vertices, [2]int{x, y})
+		}
+		return index
+	}
+
+	var process func(t rtinTriangle)
+	process = func(t rtinTriangle) {
+		mx := (t.ax + t.bx) / 2
+		my := (t.ay + t.by) / 2
+
+		if t.splittable() && errors[my*gridSize+mx] > maxError {
+			left, right := t.children()
+			process(left)
+			process(right)
+			return
+		}
+
+		m.triangles = append(m.triangles, [3]int{vertexIndex(t.ax, t.ay), vertexIndex(t.bx, t.by), vertexIndex(t.cx, t.cy)})
+	}
+
+	for _, t := range rootTriangles {
+		process(t)
+	}
+
+	return m
+}
+
+// calcErrors calculates the approximation error at the middle of the hypotenuse of every
+// splittable triangle. The error of a triangle includes the errors of its children, so a
+// triangle is always split if any of its descendants has to be split.
+func calcErrors(positions []vec3) []float64 {
+	errors := make([]float64, gridSize*gridSize)
+
+	// all triangles of a level have to be done before their parents are calculated,
+	// because the middle of a hypotenuse is shared by two triangles
+	for level := len(rtinLevels) - 1; level >= 0; level-- {
+		for _, t := range rtinLevels[level] {
+			mx := (t.ax + t.bx) / 2
+			my := (t.ay + t.by) / 2
+			middle := my*gridSize + mx
+
+			interpolated := positions[t.ay*gridSize+t.ax].add(positions[t.by*gridSize+t.bx]).scale(0.5)
+			errors[middle] = math.Max(errors[middle], interpolated.sub(positions[middle]).length())
+
+			left, right := t.children()
+			if left.splittable() {
+				errors[middle] = math.Max(errors[middle], errors[((left.ay+left.by)/2)*gridSize+(left.ax+left.bx)/2])
+			}
+			if right.splittable() {
+				errors[middle] = math.Max(errors[middle], errors[((right.ay+right.by)/2)*gridSize+(right.ax+right.bx)/2])
+			}
+		}
+	}
+
+	return errors
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
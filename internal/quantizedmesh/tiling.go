@@ -0,0 +1,126 @@
+package quantizedmesh
+
+import (
+	"math"
+
+	"github.com/gruppe-adler/meh-utils/internal/metajson"
+)
+
+// earthRadius is the radius used to place the world on the globe
+const earthRadius = 6378137.0
+
+// tileRange is a rectangle of tiles of one LOD. It is part of the layer.json.
+type tileRange struct {
+	StartX uint32 `json:"startX"`
+	StartY uint32 `json:"startY"`
+	EndX   uint32 `json:"endX"`
+	EndY   uint32 `json:"endY"`
+}
+
+// tileBounds is the extent of a tile in degrees
+type tileBounds struct {
+	west, south, east, north float64
+}
+
+// geographicTileBounds returns the bounds of a tile of the geographic tiling scheme,
+// which has two tiles in LOD 0. Rows are counted from south to north (TMS).
+func geographicTileBounds(lod uint8, col, row uint32) tileBounds {
+	size := 180.0 / float64(uint32(1)<<lod)
+
+	return tileBounds{
+		west:  -180 + float64(col)*size,
+		south: -90 + float64(row)*size,
+		east:  -180 + float64(col+1)*size,
+		north: -90 + float64(row+1)*size,
+	}
+}
+
+// worldPlacement places the square world at its position on the globe. The
+// world is projected with an equirectangular projection around its center,
+// which is precise enough for the size of Arma worlds.
+type worldPlacement struct {
+	worldSize float64
+	latitude  float64
+	longitude float64
+}
+
+func newWorldPlacement(meta metajson.MetaJSON) worldPlacement {
+	return worldPlacement{
+		worldSize: meta.WorldSize,
+		// Arma uses negative latitudes for the northern hemisphere
+		latitude:  -meta.Latitude,
+		longitude: meta.Longitude,
+	}
+}
+
+// metersPerDegreeLon is the length of one degree longitude at the world's latitude
+func (p worldPlacement) metersPerDegreeLon() float64 {
+	return earthRadius * math.Pi / 180 * math.Cos(p.latitude*math.Pi/180)
+}
+
+// metersPerDegreeLat is the length of one degree latitude
+func (p worldPlacement) metersPerDegreeLat() float64 {
+	return earthRadius * math.Pi / 180
+}
+
+// toWorld converts a longitude / latitude to world coordinates
+func (p worldPlacement) toWorld(lon, lat float64) (x, y float64) {
+	x = p.worldSize/2 + (lon-p.longitude)*p.metersPerDegreeLon()
+	y = p.worldSize/2 + (lat-p.latitude)*p.metersPerDegreeLat()
+	return x, y
+}
+
+// bounds returns the extent of the world in degrees
+func (p worldPlacement) bounds() tileBounds {
+	halfLon := p.worldSize / 2 / p.metersPerDegreeLon()
+	halfLat := p.worldSize / 2 / p.metersPerDegreeLat()
+
+	return tileBounds{
+		west:  p.longitude - halfLon,
+		south: p.latitude - halfLat,
+		east:  p.longitude + halfLon,
+		north: p.latitude + halfLat,
+	}
+}
+
+// tilesOfLod returns the range of tiles of a LOD, which overlap the world.
+// LOD 0 always includes both root tiles.
+func (p worldPlacement) tilesOfLod(lod uint8) tileRange {
+	if lod == 0 {
+		return tileRange{StartX: 0, StartY: 0, EndX: 1, EndY: 0}
+	}
+
+	b := p.bounds()
+	size := 180.0 / float64(uint32(1)<<lod)
+	maxCol := float64(uint32(2)<<lod) - 1
+	maxRow := float64(uint32(1)<<lod) - 1
+
+	toIndex := func(value, max float64) uint32 {
+		return uint32(math.Max(0, math.Min(max, math.Floor(value))))
+	}
+
+	return tileRange{
+		StartX: toIndex((b.west+180)/size, maxCol),
+		StartY: toIndex((b.south+90)/size, maxRow),
+		EndX:   toIndex((b.east+180)/size, maxCol),
+		EndY:   toIndex((b.north+90)/size, maxRow),
+	}
+}
+
+// calcMaxLod calculates the first LOD, in which the distance between the height
+// samples of a tile is smaller than the cell size of the DEM
+func calcMaxLod(cellSize float64) uint8 {
+	lod := uint8(0)
+	for earthRadius*math.Pi/float64(uint32(1)<<lod)/(gridSize-1) > cellSize && lod < 24 {
+		lod++
+	}
+	return lod
+}
+
+// levelMaxGeometricError is the geometric error Cesium expects of the tiles of a LOD
+// (see TerrainProvider.getEstimatedLevelZeroGeometricErrorForAHeightmap in CesiumJS)
+func levelMaxGeometricError(lod uint8) float64 {
+	const levelZeroError = earthRadius * 2 * math.Pi * 0.25 / (gridSize * 2)
+
+	return levelZeroError / float64(uint32(1)<<lod)
+}
@@ -1,37 +1,29 @@
 package sat
 
 import (
-	"fmt"
 	"image"
 	"image/draw"
 	"log"
 	"os"
-	"path"
 	"sync"
-
-	"github.com/gruppe-adler/meh-utils/internal/utils"
 )
 
-// combineSatImage combines the 4x4 tiles form the inputDir to a new image.RGBA
-func combineSatImage(inputDir string) *image.RGBA {
-	// holds heights of all rows
-	heights := []uint{0, 0, 0, 0}
-	widths := []uint{0, 0, 0, 0}
-
-	var images [4][4]image.Image
+// combineSatImage combines the tiles form the inputDir to a new image.RGBA
+func combineSatImage(inputDir string, grid satGrid) *image.RGBA {
+	// create new img
+	combinedImage := image.NewRGBA(grid.bounds())
 
-	// load all images
+	// load all images and draw them into the combined image
 	waitGrp := sync.WaitGroup{}
-	for col := 0; col < 4; col++ {
-		for row := 0; row < 4; row++ {
+	for col := 0; col < grid.cols(); col++ {
+		for row := 0; row < grid.rows(); row++ {
 			waitGrp.Add(1)
 
 			go func(col int, row int) {
 				defer waitGrp.Done()
 
 				// open image
-				filePath := path.Join(inputDir, fmt.Sprintf("%d", col), fmt.Sprintf("%d.png", row))
-				file, err := os.Open(filePath)
+				file, err := os.Open(satTilePath(inputDir, col, row))
 				if err != nil {
 					log.Fatal(err)
 				}
@@ -40,52 +32,28 @@ func combineSatImage(inputDir string) *image.RGBA {
 					log.Fatal(err)
 				}
 
-				file.Close()
+				err = file.Close()
 				if err != nil {
 					log.Fatal(err)
 				}
 
-				// save in structure
-				images[col][row] = img
+				// the tiles don't overlap, so they can be drawn concurrently
+				r := grid.tileRect(col, row)
+				draw.Draw(combinedImage, r, img, image.Point{0, 0}, draw.Src)
 			}(col, row)
 		}
 	}
 	waitGrp.Wait()
 
-	// update heights / widths
-	for col := 0; col < 4; col++ {
-		for row := 0; row < 4; row++ {
-			img := images[col][row]
-
-			imgWidth := uint(img.Bounds().Dx())
-			if imgWidth > widths[col] {
-				widths[col] = imgWidth
-			}
-			imgHeight := uint(img.Bounds().Dy())
-			if imgHeight > heights[row] {
-				heights[row] = imgHeight
-			}
-		}
-	}
-
-	width := int(utils.Sum(widths))
-	height := int(utils.Sum(heights))
-
-	// create new img
-	combinedImage := image.NewRGBA(image.Rectangle{image.Point{0, 0}, image.Point{width, height}})
-
-	for col := 0; col < 4; col++ {
-		for row := 0; row < 4; row++ {
-			img := &images[col][row]
-
-			x := int(utils.Sum(widths[0:col]))
-			y := int(utils.Sum(heights[0:row]))
-			upperLeftPoint := image.Point{x, y}
-			r := image.Rectangle{upperLeftPoint, upperLeftPoint.Add((*img).Bounds().Size())}
+	return combinedImage
+}
 
-			draw.Draw(combinedImage, r, *img, image.Point{0, 0}, draw.Src)
-		}
+// CombineImage combines the satellite image tiles of the grad_meh sat directory to one image
+func CombineImage(inputDir string) (*image.RGBA, error) {
+	grid, err := readSatGrid(inputDir)
+	if err != nil {
+		return nil, err
 	}
 
-	return combinedImage
+	return combineSatImage(inputDir, grid), nil
 }
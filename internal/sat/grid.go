@@ -0,0 +1,91 @@
+package sat
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path"
+
+	"github.com/gruppe-adler/meh-utils/internal/utils"
+	"github.com/gruppe-adler/meh-utils/internal/validate"
+)
+
+// satGrid describes the layout of the tiles in a grad_meh sat directory
+type satGrid struct {
+	// width of each column / height of each row
+	widths  []uint
+	heights []uint
+}
+
+// readSatGrid finds the columns and rows of the tiles from the inputDir and reads their dimensions.
+// All tiles of a column must have the same width and all tiles of a row must have the same height.
+func readSatGrid(inputDir string) (satGrid, error) {
+	cols, rows, err := validate.SatGrid(inputDir)
+	if err != nil {
+		return satGrid{}, err
+	}
+
+	grid := satGrid{
+		widths:  make([]uint, cols),
+		heights: make([]uint, rows),
+	}
+
+	// only read the dimensions of all images
+	for col := 0; col < cols; col++ {
+		for row := 0; row < rows; row++ {
+			filePath := satTilePath(inputDir, col, row)
+
+			file, err := os.Open(filePath)
+			if err != nil {
+				return satGrid{}, err
+			}
+
+			config, _, err := image.DecodeConfig(file)
+			file.Close()
+			if err != nil {
+				return satGrid{}, fmt.Errorf("%s: %s", filePath, err)
+			}
+
+			if row == 0 {
+				grid.widths[col] = uint(config.Width)
+			} else if grid.widths[col] != uint(config.Width) {
+				return satGrid{}, fmt.Errorf("%s is %dpx wide, but the other tiles of column %d are %dpx wide", filePath, config.Width, col, grid.widths[col])
+			}
+
+			if col == 0 {
+				grid.heights[row] = uint(config.Height)
+			} else if grid.heights[row] != uint(config.Height) {
+				return satGrid{}, fmt.Errorf("%s is %dpx high, but the other tiles of row %d are %dpx high", filePath, config.Height, row, grid.heights[row])
+			}
+		}
+	}
+
+	return grid, nil
+}
+
+// cols returns the number of columns
+func (grid satGrid) cols() int {
+	return len(grid.widths)
+}
+
+// rows returns the number of rows
+func (grid satGrid) rows() int {
+	return len(grid.heights)
+}
+
+// tileRect returns the rectangle the tile at col/row covers in the combined image
+func (grid satGrid) tileRect(col int, row int) image.Rectangle {
+	x := int(utils.Sum(grid.widths[0:col]))
+	y := int(utils.Sum(grid.heights[0:row]))
+
+	return image.Rect(x, y, x+int(grid.widths[col]), y+int(grid.heights[row]))
+}
+
+// bounds returns the bounds of the combined image
+func (grid satGrid) bounds() image.Rectangle {
+	return image.Rect(0, 0, int(utils.Sum(grid.widths)), int(utils.Sum(grid.heights)))
+}
+
+func satTilePath(inputDir string, col int, row int) string {
+	return path.Join(inputDir, fmt.Sprintf("%d", col), fmt.Sprintf("%d.png", row))
+}
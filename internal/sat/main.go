@@ -7,12 +7,13 @@ import (
 	"log"
 	"os"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/gruppe-adler/meh-utils/internal/metajson"
 	"github.com/gruppe-adler/meh-utils/internal/tilejson"
+	"github.com/gruppe-adler/meh-utils/internal/tilewriter"
 	"github.com/gruppe-adler/meh-utils/internal/utils"
-	"github.com/gruppe-adler/meh-utils/internal/validate"
 )
 
 // Run is the program's entrypoint
@@ -21,8 +22,11 @@ func Run(flagSet *flag.FlagSet) {
 	var timer time.Time
 	start := time.Now()
 
-	outputPtr := flagSet.String("out", "", "Path to output directory")
+	outputPtr := flagSet.String("out", "", "Path to output directory (or output file for -format mbtiles / pmtiles)")
 	inputPtr := flagSet.String("in", "", "Path to grad_meh map directory")
+	formatPtr := flagSet.String("format", tilewriter.Directory, fmt.Sprintf("Output format (%s)", strings.Join(tilewriter.Formats, ", ")))
+	streamPtr := flagSet.Bool("stream", false, "Only read the needed parts of the satellite image instead of combining the whole image in memory")
+	cachePtr := flagSet.Int("cache", 4, "Number of decoded source images to keep in memory with -stream")
 
 	flagSet.Parse(os.Args[2:])
 
@@ -32,20 +36,22 @@ func Run(flagSet *flag.FlagSet) {
 		os.Exit(1)
 	}
 
-	// make sure given output directory is a valid directory
-	if !utils.IsDirectory(*outputPtr) {
-		log.Fatal(errors.New("Output directory doesn't exists"))
+	// create tile writer (makes sure the output path is valid)
+	writer, err := tilewriter.New(*formatPtr, *outputPtr, "png")
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	inputDir := path.Join(*inputPtr, "sat")
 
 	// validate input directory structure
-	err := validate.SatDirectory(inputDir)
+	grid, err := readSatGrid(inputDir)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	fmt.Println("✔️  Validated input directory structure")
+	fmt.Printf("ℹ️  Found %dx%d satellite image tiles\n", grid.cols(), grid.rows())
 
 	// load meta.json
 	timer = time.Now()
@@ -56,31 +62,35 @@ func Run(flagSet *flag.FlagSet) {
 	}
 	fmt.Println("✔️  Loaded meta.json in", time.Now().Sub(timer).String())
 
-	// combine sat image
-	timer = time.Now()
-	fmt.Println("▶️  Combining satellite image")
-	combinedImg := combineSatImage(inputDir)
-	fmt.Println("✔️  Combined satellite image in", time.Now().Sub(timer).String())
+	var satImg utils.ImageSource
+	if *streamPtr {
+		satImg = newSatMosaic(inputDir, grid, *cachePtr)
+	} else {
+		// combine sat image
+		timer = time.Now()
+		fmt.Println("▶️  Combining satellite image")
+		satImg = combineSatImage(inputDir, grid)
+		fmt.Println("✔️  Combined satellite image in", time.Now().Sub(timer).String())
+	}
 
 	// combine max LOD
-	maxLod := utils.CalcMaxLodFromImage(combinedImg)
+	maxLod := utils.CalcMaxLodFromImage(satImg)
 	fmt.Println("ℹ️  Calculated max lod:", maxLod)
 
 	// build tiles
 	timer = time.Now()
 	fmt.Println("▶️  Building tiles")
-	for lod := uint8(0); lod <= maxLod; lod++ {
-		timer2 := time.Now()
-		utils.BuildTileSet(lod, combinedImg, *outputPtr)
-		fmt.Println("    ✔️  Finished tiles for LOD", lod, "in", time.Now().Sub(timer2).String())
-	}
+	utils.BuildTilePyramid(maxLod, satImg, utils.RGBAResampler{}, writer)
 	fmt.Println("✔️  Built sat tiles in", time.Now().Sub(timer).String())
 
-	// write tile.json
+	// write tile.json / metadata
 	timer = time.Now()
-	fmt.Println("▶️  Creating tile.json")
-	tilejson.Write(*outputPtr, maxLod, meta, "Satellite", []string{})
-	fmt.Println("✔️  Created tile.json in", time.Now().Sub(timer).String())
+	fmt.Println("▶️  Writing tileset metadata")
+	err = writer.Close(tilejson.New(maxLod, meta, "Satellite", []string{}))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("✔️  Wrote tileset metadata in", time.Now().Sub(timer).String())
 
 	fmt.Printf("\n    🎉  Finished in %s\n", time.Now().Sub(start).String())
 }
@@ -0,0 +1,130 @@
+package sat
+
+import (
+	"container/list"
+	"image"
+	"image/draw"
+	"log"
+	"os"
+	"sync"
+)
+
+// satMosaic provides rectangles of the satellite image without combining
+// the whole image. Only the source tiles which overlap a requested rectangle
+// are decoded and a bounded number of decoded source tiles is cached.
+type satMosaic struct {
+	inputDir string
+	grid     satGrid
+	cache    *imageCache
+}
+
+// newSatMosaic creates a satMosaic for the tiles from the inputDir, which keeps at most cacheSize decoded tiles in memory
+func newSatMosaic(inputDir string, grid satGrid, cacheSize int) *satMosaic {
+	return &satMosaic{
+		inputDir: inputDir,
+		grid:     grid,
+		cache:    newImageCache(cacheSize),
+	}
+}
+
+// Bounds returns the bounds of the combined satellite image
+func (m *satMosaic) Bounds() image.Rectangle {
+	return m.grid.bounds()
+}
+
+// SubImage returns the given rectangle of the combined satellite image
+func (m *satMosaic) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(m.Bounds())
+	img := image.NewRGBA(r)
+
+	for col := 0; col < m.grid.cols(); col++ {
+		for row := 0; row < m.grid.rows(); row++ {
+			tileRect := m.grid.tileRect(col, row)
+
+			overlap := r.Intersect(tileRect)
+			if overlap.Empty() {
+				continue
+			}
+
+			tile, err := m.cache.Get(satTilePath(m.inputDir, col, row))
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			draw.Draw(img, overlap, tile, overlap.Min.Sub(tileRect.Min), draw.Src)
+		}
+	}
+
+	return img
+}
+
+// imageCache is a LRU cache of decoded images, which is safe for concurrent use
+type imageCache struct {
+	size    int
+	entries map[string]*list.Element
+	lru     *list.List
+	mux     sync.Mutex
+}
+
+type imageCacheEntry struct {
+	path  string
+	img   image.Image
+	err   error
+	ready chan struct{}
+}
+
+func newImageCache(size int) *imageCache {
+	if size < 1 {
+		size = 1
+	}
+
+	return &imageCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Get returns the decoded image at given path. Every image is decoded only once
+// while it is cached, even if it is requested by multiple goroutines at once.
+func (c *imageCache) Get(imgPath string) (image.Image, error) {
+	c.mux.Lock()
+
+	if el, found := c.entries[imgPath]; found {
+		c.lru.MoveToFront(el)
+		entry := el.Value.(*imageCacheEntry)
+		c.mux.Unlock()
+
+		<-entry.ready
+		return entry.img, entry.err
+	}
+
+	entry := &imageCacheEntry{path: imgPath, ready: make(chan struct{})}
+	c.entries[imgPath] = c.lru.PushFront(entry)
+
+	// evict least recently used images
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*imageCacheEntry).path)
+	}
+
+	c.mux.Unlock()
+
+	entry.img, entry.err = decodeImage(imgPath)
+	close(entry.ready)
+
+	return entry.img, entry.err
+}
+
+func decodeImage(imgPath string) (image.Image, error) {
+	file, err := os.Open(imgPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+
+	return img, err
+}
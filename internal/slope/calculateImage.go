@@ -0,0 +1,39 @@
+package slope
+
+import (
+	"image"
+	"math"
+
+	"github.com/gruppe-adler/meh-utils/internal/colorramp"
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+)
+
+// units in which the slope can be classified
+const (
+	Degrees = "degrees"
+	Percent = "percent"
+)
+
+func calculateImage(raster dem.EsriASCIIRaster, unit string, ramp colorramp.Ramp) *image.RGBA {
+	w, h := raster.Dims()
+
+	img := image.NewRGBA(image.Rectangle{image.Point{0, 0}, image.Point{int(w), int(h)}})
+
+	for col := uint(0); col < w; col++ {
+		for row := uint(0); row < h; row++ {
+			// cells without data stay transparent
+			if raster.IsNoData(col, row) {
+				continue
+			}
+
+			slope := raster.Slope(col, row)
+			if unit == Percent {
+				slope = math.Tan(slope*math.Pi/180) * 100
+			}
+
+			img.Set(int(col), int(row), ramp.Color(slope))
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,33 @@
+package slope
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"log"
+
+	"github.com/gruppe-adler/meh-utils/internal/colorramp"
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+	"github.com/gruppe-adler/meh-utils/internal/demtiles"
+)
+
+// Run is the program's entrypoint
+func Run(flagSet *flag.FlagSet) {
+	flags := demtiles.AddFlags(flagSet, "slopes in -unit")
+	unitPtr := flagSet.String("unit", Degrees, fmt.Sprintf("Unit of the slope (%s, %s)", Degrees, Percent))
+
+	flags.Parse(flagSet)
+
+	if *unitPtr != Degrees && *unitPtr != Percent {
+		log.Fatal(fmt.Errorf("Unknown unit: %s", *unitPtr))
+	}
+
+	ramp := colorramp.SlopeDegrees
+	if *unitPtr == Percent {
+		ramp = colorramp.SlopePercent
+	}
+
+	demtiles.Build(flags, "slope", ramp, func(raster dem.EsriASCIIRaster, ramp colorramp.Ramp) *image.RGBA {
+		return calculateImage(raster, *unitPtr, ramp)
+	})
+}
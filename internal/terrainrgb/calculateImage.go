@@ -6,7 +6,7 @@ import (
 	dem "github.com/gruppe-adler/meh-utils/internal/dem"
 )
 
-func calculateImage(dem dem.EsriASCIIRaster, elevationOffset float64) *image.RGBA {
+func calculateImage(dem dem.EsriASCIIRaster, elevationOffset float64, encoding Encoding) *image.RGBA {
 
 	w, h := dem.Dims()
 
@@ -14,7 +14,12 @@ func calculateImage(dem dem.EsriASCIIRaster, elevationOffset float64) *image.RGB
 
 	for col := uint(0); col < w; col++ {
 		for row := uint(0); row < h; row++ {
-			color := HeightToRgb(dem.Z(col, row) + elevationOffset)
+			// cells without data stay transparent
+			if dem.IsNoData(col, row) {
+				continue
+			}
+
+			color := encoding.Encode(dem.Z(col, row) + elevationOffset)
 
 			img.SetRGBA(int(col), int(row), color)
 		}
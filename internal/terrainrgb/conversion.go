@@ -26,7 +26,8 @@ var MAX_X = int64(math.Pow(256, 3) - 1)
 
 // HeightToRgb calculates rgb values from height
 func HeightToRgb(height float64) color.RGBA {
-	x := int64(10*height+100000) % MAX_X
+	// round to the nearest 0.1m, so decoding and encoding a height again yields the same color
+	x := int64(math.Round(10*height+100000)) % MAX_X
 
 	b := uint8(x % 256)
 	x = int64(x / 256)
@@ -51,3 +52,52 @@ func RgbToHeight(color color.RGBA) float64 {
 
 	return float64(-10000.0) + (float64(x) * 0.1)
 }
+
+/*
+	The Terrarium encoding (used by Tangram, MapLibre, deck.gl, ...) uses the following equation:
+
+	height = (R * 256 + G + B / 256) - 32768
+
+	So R and G hold the whole meters and B the fractional part of (height + 32768).
+*/
+
+// HeightToTerrarium calculates Terrarium rgb values from height
+func HeightToTerrarium(height float64) color.RGBA {
+	// round to the nearest 1/256m, so decoding and encoding a height again yields the same color
+	x := math.Round((height + 32768) * 256)
+
+	// clamp to the range the encoding can represent
+	x = math.Max(0, math.Min(x, math.Pow(256, 3)-1))
+
+	v := int64(x)
+
+	return color.RGBA{
+		R: uint8(v / 256 / 256),
+		G: uint8(v / 256 % 256),
+		B: uint8(v % 256),
+		A: 255,
+	}
+}
+
+// TerrariumToHeight calculates height from given Terrarium rgb values
+func TerrariumToHeight(color color.RGBA) float64 {
+	return float64(color.R)*256 + float64(color.G) + float64(color.B)/256 - 32768
+}
+
+// Encoding describes how heights are encoded as colors
+type Encoding struct {
+	// Name is the name of the encoding as it is used by raster-dem sources (i.e. in the tile.json)
+	Name string
+
+	// Title is a human readable name of the tileset
+	Title string
+
+	Encode func(height float64) color.RGBA
+	Decode func(color color.RGBA) float64
+}
+
+// Encodings holds all supported encodings by their name
+var Encodings = map[string]Encoding{
+	"mapbox":    {Name: "mapbox", Title: "Mapbox Terrain-RGB", Encode: HeightToRgb, Decode: RgbToHeight},
+	"terrarium": {Name: "terrarium", Title: "Terrarium", Encode: HeightToTerrarium, Decode: TerrariumToHeight},
+}
@@ -7,11 +7,13 @@ import (
 	"log"
 	"os"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/gruppe-adler/meh-utils/internal/dem"
 	"github.com/gruppe-adler/meh-utils/internal/metajson"
 	"github.com/gruppe-adler/meh-utils/internal/tilejson"
+	"github.com/gruppe-adler/meh-utils/internal/tilewriter"
 	"github.com/gruppe-adler/meh-utils/internal/utils"
 	"github.com/gruppe-adler/meh-utils/internal/validate"
 )
@@ -24,8 +26,12 @@ func Run(flagSet *flag.FlagSet) {
 	var timer time.Time
 	start := time.Now()
 
-	outputPtr := flagSet.String("out", "", "Path to output directory")
+	outputPtr := flagSet.String("out", "", "Path to output directory (or output file for -format mbtiles / pmtiles)")
 	inputPtr := flagSet.String("in", "", "Path to grad_meh map directory")
+	formatPtr := flagSet.String("format", tilewriter.Directory, fmt.Sprintf("Output format (%s)", strings.Join(tilewriter.Formats, ", ")))
+	downsamplePtr := flagSet.String("downsample", "mean", "How heights are aggregated for lower LODs (mean, min, max)")
+	encodingPtr := flagSet.String("encoding", "mapbox", "How heights are encoded as colors (mapbox, terrarium)")
+	demPtr := flagSet.String("dem", "", "Path to DEM (ESRI ASCII grid, XYZ, GeoTIFF or ESRI float grid), defaults to the dem.asc.gz of the grad_meh map directory")
 
 	flagSet.Parse(os.Args[2:])
 
@@ -35,13 +41,29 @@ func Run(flagSet *flag.FlagSet) {
 		os.Exit(1)
 	}
 
-	// make sure given output directory is a valid directory
-	if !utils.IsDirectory(*outputPtr) {
-		log.Fatal(errors.New("Output directory doesn't exists"))
+	demPath := *demPtr
+	if demPath == "" {
+		demPath = path.Join(*inputPtr, "dem.asc.gz")
+	}
+
+	// create tile writer (makes sure the output path is valid)
+	writer, err := tilewriter.New(*formatPtr, *outputPtr, "png")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	encoding, found := Encodings[*encodingPtr]
+	if !found {
+		log.Fatal(fmt.Errorf("Unknown encoding: %s", *encodingPtr))
+	}
+
+	resampler, err := newHeightResampler(encoding, *downsamplePtr)
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	// validate input directory structure
-	err := validate.MehDirectory(*inputPtr)
+	err = validate.MehDirectoryWithDEM(*inputPtr, demPath)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -60,13 +82,13 @@ func Run(flagSet *flag.FlagSet) {
 	// load DEM
 	timer = time.Now()
 	fmt.Println("▶️  Loading DEM")
-	dem := dem.Read(path.Join(*inputPtr, "dem.asc.gz"))
+	dem := dem.Read(demPath)
 	fmt.Println("✔️  Loaded DEM in", time.Now().Sub(timer).String())
 
 	// calculating image
 	timer = time.Now()
 	fmt.Println("▶️  Calculating image from DEM")
-	img := calculateImage(dem, meta.ElevationOffset)
+	img := calculateImage(dem, meta.ElevationOffset, encoding)
 	fmt.Println("✔️  Calculated image in", time.Now().Sub(timer).String())
 
 	// calculate max LOD
@@ -76,18 +98,19 @@ func Run(flagSet *flag.FlagSet) {
 	// build tiles
 	timer = time.Now()
 	fmt.Println("▶️  Building tiles")
-	for lod := uint8(0); lod <= maxLod; lod++ {
-		timer2 := time.Now()
-		utils.BuildTileSet(lod, img, *outputPtr)
-		fmt.Println("    ✔️  Finished tiles for LOD", lod, "in", time.Now().Sub(timer2).String())
-	}
-	fmt.Println("✔️  Built Terrain-RGB tiles in", time.Now().Sub(timer).String())
+	utils.BuildTilePyramid(maxLod, img, resampler, writer)
+	fmt.Println("✔️  Built", encoding.Title, "tiles in", time.Now().Sub(timer).String())
 
-	// write tile.json
+	// write tile.json / metadata
 	timer = time.Now()
-	fmt.Println("▶️  Creating tile.json")
-	tilejson.Write(*outputPtr, maxLod, meta, "Mapbox Terrain-RGB", []string{})
-	fmt.Println("✔️  Created tile.json in", time.Now().Sub(timer).String())
+	fmt.Println("▶️  Writing tileset metadata")
+	tileJSON := tilejson.New(maxLod, meta, encoding.Title, []string{})
+	tileJSON.Encoding = encoding.Name
+	err = writer.Close(tileJSON)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("✔️  Wrote tileset metadata in", time.Now().Sub(timer).String())
 
 	fmt.Printf("\n    🎉  Finished in %s\n", time.Now().Sub(start).String())
 }
@@ -0,0 +1,139 @@
+package terrainrgb
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/gruppe-adler/meh-utils/internal/utils"
+)
+
+// aggregations which can be used to downsample heights. They are called with one to four heights.
+var aggregations = map[string]func(heights []float64) float64{
+	"mean": func(heights []float64) float64 {
+		sum := float64(0)
+		for _, h := range heights {
+			sum += h
+		}
+		return sum / float64(len(heights))
+	},
+	"min": func(heights []float64) float64 {
+		min := heights[0]
+		for _, h := range heights[1:] {
+			min = math.Min(min, h)
+		}
+		return min
+	},
+	"max": func(heights []float64) float64 {
+		max := heights[0]
+		for _, h := range heights[1:] {
+			max = math.Max(max, h)
+		}
+		return max
+	},
+}
+
+// heightResampler resamples Terrain-RGB / Terrarium tiles in the height domain. Interpolating
+// the packed R, G and B channels directly would result in nonsense heights. Transparent
+// pixels have no data and are left out, so they don't drag down their neighbors.
+type heightResampler struct {
+	encoding  Encoding
+	aggregate func(heights []float64) float64
+}
+
+func newHeightResampler(encoding Encoding, aggregation string) (heightResampler, error) {
+	aggregate, found := aggregations[aggregation]
+	if !found {
+		return heightResampler{}, fmt.Errorf("Unknown downsample aggregation: %s", aggregation)
+	}
+
+	return heightResampler{encoding: encoding, aggregate: aggregate}, nil
+}
+
+// Resize scales given encoded image to the size of a tile by bilinear interpolation of the heights.
+// Pixels without data are ignored and only pixels without any neighbors with data stay transparent.
+func (r heightResampler) Resize(img image.Image) *image.RGBA {
+	src := utils.ToRGBA(img)
+	bounds := src.Bounds()
+	tile := image.NewRGBA(image.Rect(0, 0, utils.TileSizeInPx, utils.TileSizeInPx))
+
+	scaleX := float64(bounds.Dx()) / utils.TileSizeInPx
+	scaleY := float64(bounds.Dy()) / utils.TileSizeInPx
+
+	// accumulates the weighted height of the pixel, if it has data
+	var sum, weights float64
+	add := func(x, y int, weight float64) {
+		c := src.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+		if c.A == 0 || weight == 0 {
+			return
+		}
+		sum += weight * r.encoding.Decode(c)
+		weights += weight
+	}
+
+	for y := 0; y < utils.TileSizeInPx; y++ {
+		// position of the pixel center in the source image
+		srcY := clamp((float64(y)+0.5)*scaleY-0.5, 0, float64(bounds.Dy()-1))
+		y0 := int(srcY)
+		y1 := minInt(y0+1, bounds.Dy()-1)
+		fy := srcY - float64(y0)
+
+		for x := 0; x < utils.TileSizeInPx; x++ {
+			srcX := clamp((float64(x)+0.5)*scaleX-0.5, 0, float64(bounds.Dx()-1))
+			x0 := int(srcX)
+			x1 := minInt(x0+1, bounds.Dx()-1)
+			fx := srcX - float64(x0)
+
+			sum, weights = 0, 0
+			add(x0, y0, (1-fx)*(1-fy))
+			add(x1, y0, fx*(1-fy))
+			add(x0, y1, (1-fx)*fy)
+			add(x1, y1, fx*fy)
+
+			if weights > 0 {
+				tile.SetRGBA(x, y, r.encoding.Encode(sum/weights))
+			}
+		}
+	}
+
+	return tile
+}
+
+// Downsample merges four tiles into one tile by aggregating the heights of each 2x2 block of pixels
+func (r heightResampler) Downsample(children [4]*image.RGBA) *image.RGBA {
+	tile := image.NewRGBA(image.Rect(0, 0, utils.TileSizeInPx, utils.TileSizeInPx))
+
+	heights := make([]float64, 0, 4)
+	utils.DownsampleQuadrants(tile, children, func(pixels [4][]uint8, dst []uint8) {
+		heights = heights[:0]
+		for _, p := range pixels {
+			if p[3] == 0 {
+				continue
+			}
+			heights = append(heights, r.encoding.Decode(color.RGBA{p[0], p[1], p[2], p[3]}))
+		}
+
+		// stay transparent if none of the pixels has data
+		if len(heights) == 0 {
+			dst[0], dst[1], dst[2], dst[3] = 0, 0, 0, 0
+			return
+		}
+
+		c := r.encoding.Encode(r.aggregate(heights))
+		dst[0], dst[1], dst[2], dst[3] = c.R, c.G, c.B, c.A
+	})
+
+	return tile
+}
+
+func clamp(v, lower, upper float64) float64 {
+	return math.Max(lower, math.Min(upper, v))
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -1,6 +1,11 @@
 package tilejson
 
-var contourLayerFields = map[string]string{"elevation": "Corrected elevation of contour. (Includes elevationOffset)", "dem_elevation": "DEM elevation of contour."}
+// ContourLayerFields are the fields of the contour layers, which are declared in the layer settings of mvt
+var ContourLayerFields = map[string]string{
+	"elevation":     "Corrected elevation of contour. (Includes elevationOffset)",
+	"dem_elevation": "DEM elevation of contour.",
+	"index":         "Whether the contour is an index contour.",
+}
 
 var locationLayerFields = map[string]string{
 	"name":    "Corresponds to value in map config.",
@@ -10,11 +15,9 @@ var locationLayerFields = map[string]string{
 }
 
 var vectorLayerFields = map[string]map[string]string{
-	"contours/01":                   contourLayerFields,
-	"contours/05":                   contourLayerFields,
-	"contours/10":                   contourLayerFields,
-	"contours/100":                  contourLayerFields,
-	"contours/50":                   contourLayerFields,
+	"bathymetry":                    {"min_depth": "Lower depth of a depth area in meters below sea level.", "max_depth": "Upper depth of a depth area in meters below sea level.", "depth": "Depth of a depth contour in meters below sea level."},
+	"contour_labels":                {"elevation": "Corrected elevation of the index contour as a string. (Includes elevationOffset)", "dem_elevation": "DEM elevation of the index contour.", "angle": "Rotation of the label along the contour in degrees (clockwise)"},
+	"elevation_bands":               {"min": "Corrected lower elevation of the band. (Includes elevationOffset)", "max": "Corrected upper elevation of the band. (Includes elevationOffset)"},
 	"house":                         {"color": "House color as a CSS rgb() string.", "height": "Height of the building in meters"},
 	"mount":                         {"elevation": "Elevation as float", "text": "Rounded elevation as a string"},
 	"locations/respawn_unknown":     locationLayerFields,
@@ -9,10 +9,8 @@ import (
 	"github.com/gruppe-adler/meh-utils/internal/metajson"
 )
 
-// Write a tile.json
-func Write(outputDirectory string, maxLod uint8, meta metajson.MetaJSON, layerName string, vectorLayerNames []string) error {
-	var err error
-
+// New builds the tile.json of a tileset
+func New(maxLod uint8, meta metajson.MetaJSON, layerName string, vectorLayerNames []string) TileJSON {
 	// build vector layers
 	vectorLayers := make([]VectorLayer, len(vectorLayerNames))
 	for i, layerName := range vectorLayerNames {
@@ -28,7 +26,7 @@ func Write(outputDirectory string, maxLod uint8, meta metajson.MetaJSON, layerNa
 		}
 	}
 
-	obj := TileJSON{
+	return TileJSON{
 		TileJSON:     "2.2.0",
 		Name:         fmt.Sprintf("%s %s Tiles", meta.DisplayName, layerName),
 		Description:  fmt.Sprintf("%s Tiles of the Arma 3 Map '%s' from %s", layerName, meta.DisplayName, meta.Author),
@@ -37,6 +35,11 @@ func Write(outputDirectory string, maxLod uint8, meta metajson.MetaJSON, layerNa
 		Maxzoom:      maxLod,
 		VectorLayers: vectorLayers,
 	}
+}
+
+// Write a tile.json
+func Write(outputDirectory string, obj TileJSON) error {
+	var err error
 
 	// create file
 	f, err := os.Create(path.Join(outputDirectory, "tile.json"))
@@ -15,4 +15,5 @@ type TileJSON struct {
 	Minzoom      uint8         `json:"minzoom"`
 	Maxzoom      uint8         `json:"maxzoom"`
 	VectorLayers []VectorLayer `json:"vector_layers,omitempty"`
+	Encoding     string        `json:"encoding,omitempty"`
 }
@@ -0,0 +1,82 @@
+package tilewriter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/gruppe-adler/meh-utils/internal/tilejson"
+	"github.com/gruppe-adler/meh-utils/internal/utils"
+)
+
+// directoryWriter writes tiles as loose files to {lod}/{col}/{row}.{ext}
+type directoryWriter struct {
+	outputDirectory string
+	ext             string
+
+	// col directories which were already created
+	createdDirs map[string]bool
+	dirsMux     sync.Mutex
+}
+
+func newDirectoryWriter(outputDirectory string, ext string) (*directoryWriter, error) {
+	// make sure given output directory is a valid directory
+	if !utils.IsDirectory(outputDirectory) {
+		return nil, errors.New("Output directory doesn't exists")
+	}
+
+	return &directoryWriter{
+		outputDirectory: outputDirectory,
+		ext:             ext,
+		createdDirs:     make(map[string]bool),
+	}, nil
+}
+
+func (w *directoryWriter) WriteTile(lod uint8, col uint32, row uint32, data []byte) error {
+	dirPath := path.Join(w.outputDirectory, fmt.Sprintf("%d", lod), fmt.Sprintf("%d", col))
+
+	err := w.ensureDirectory(dirPath)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path.Join(dirPath, fmt.Sprintf("%d.%s", row, w.ext)))
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (w *directoryWriter) Close(metadata tilejson.TileJSON) error {
+	return tilejson.Write(w.outputDirectory, metadata)
+}
+
+// ensureDirectory creates given directory, if it wasn't created by this writer already
+func (w *directoryWriter) ensureDirectory(dirPath string) error {
+	w.dirsMux.Lock()
+	defer w.dirsMux.Unlock()
+
+	if w.createdDirs[dirPath] {
+		return nil
+	}
+
+	if !utils.IsDirectory(dirPath) {
+		err := os.MkdirAll(dirPath, os.ModePerm)
+		if err != nil {
+			return err
+		}
+	}
+
+	w.createdDirs[dirPath] = true
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package tilewriter
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+
+	// registers the "sqlite3" database/sql driver
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/gruppe-adler/meh-utils/internal/tilejson"
+	"github.com/gruppe-adler/meh-utils/internal/utils"
+)
+
+const mbtilesSchema = `
+CREATE TABLE metadata (name TEXT, value TEXT);
+CREATE UNIQUE INDEX metadata_index ON metadata (name);
+CREATE TABLE tiles (zoom_level INTEGER, tile_column INTEGER, tile_row INTEGER, tile_data BLOB);
+CREATE UNIQUE INDEX tile_index ON tiles (zoom_level, tile_column, tile_row);
+`
+
+// mbtilesWriter writes all tiles into a single MBTiles 1.3 file
+// (see https://github.com/mapbox/mbtiles-spec/blob/master/1.3/spec.md)
+type mbtilesWriter struct {
+	db         *sql.DB
+	tx         *sql.Tx
+	insert     *sql.Stmt
+	tileFormat string
+	mux        sync.Mutex
+}
+
+func newMBTilesWriter(filePath string, tileFormat string) (*mbtilesWriter, error) {
+	// make sure the directory of the output file exists
+	if !utils.IsDirectory(path.Dir(filePath)) {
+		return nil, errors.New("Directory of output file doesn't exists")
+	}
+
+	if utils.IsDirectory(filePath) {
+		return nil, fmt.Errorf("%s is a directory, but the mbtiles format needs a file path", filePath)
+	}
+
+	// the tileset is always written from scratch
+	if utils.IsFile(filePath) {
+		err := os.Remove(filePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := sql.Open("sqlite3", filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// all writes happen in one transaction, so there is only ever one connection
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("PRAGMA synchronous = OFF; PRAGMA journal_mode = OFF;")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	_, err = db.Exec(mbtilesSchema)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	insert, err := tx.Prepare("INSERT INTO tiles (zoom_level, tile_column, tile_row, tile_data) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		db.Close()
+		return nil, err
+	}
+
+	return &mbtilesWriter{
+		db:         db,
+		tx:         tx,
+		insert:     insert,
+		tileFormat: tileFormat,
+	}, nil
+}
+
+func (w *mbtilesWriter) WriteTile(lod uint8, col uint32, row uint32, data []byte) error {
+	// MBTiles uses the TMS tiling scheme, which has its origin in the bottom left corner
+	tmsRow := (uint32(1) << lod) - 1 - row
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	_, err := w.insert.Exec(lod, col, tmsRow, data)
+
+	return err
+}
+
+func (w *mbtilesWriter) Close(metadata tilejson.TileJSON) error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	rows := map[string]string{
+		"name":        metadata.Name,
+		"description": metadata.Description,
+		"format":      w.tileFormat,
+		"minzoom":     fmt.Sprintf("%d", metadata.Minzoom),
+		"maxzoom":     fmt.Sprintf("%d", metadata.Maxzoom),
+		"bounds":      "-180.0,-85.0511,180.0,85.0511",
+		"center":      fmt.Sprintf("0,0,%d", metadata.Minzoom),
+		"type":        "baselayer",
+	}
+
+	// encoding of raster-dem tilesets
+	if metadata.Encoding != "" {
+		rows["encoding"] = metadata.Encoding
+	}
+
+	// vector tilesets have to list their layers in the json row
+	if len(metadata.VectorLayers) > 0 {
+		bytes, err := json.Marshal(struct {
+			VectorLayers []tilejson.VectorLayer `json:"vector_layers"`
+		}{metadata.VectorLayers})
+		if err != nil {
+			return err
+		}
+
+		rows["json"] = string(bytes)
+		rows["type"] = "overlay"
+	}
+
+	for name, value := range rows {
+		_, err := w.tx.Exec("INSERT INTO metadata (name, value) VALUES (?, ?)", name, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := w.insert.Close()
+	if err != nil {
+		return err
+	}
+
+	err = w.tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return w.db.Close()
+}
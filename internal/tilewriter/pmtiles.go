@@ -0,0 +1,438 @@
+package tilewriter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/gruppe-adler/meh-utils/internal/tilejson"
+	"github.com/gruppe-adler/meh-utils/internal/utils"
+)
+
+// see https://github.com/protomaps/PMTiles/blob/main/spec/v3/spec.md
+const (
+	pmtilesHeaderLength  = 127
+	pmtilesMaxRootLength = 16384 - pmtilesHeaderLength
+
+	pmtilesCompressionNone = 1
+	pmtilesCompressionGzip = 2
+
+	pmtilesTileTypeMvt = 1
+	pmtilesTileTypePng = 2
+)
+
+// pmtilesEntry is an entry of a PMTiles directory
+type pmtilesEntry struct {
+	TileID    uint64
+	Offset    uint64
+	Length    uint32
+	RunLength uint32
+}
+
+// pmtilesTile is a tile which was written, but not yet placed in the archive
+type pmtilesTile struct {
+	TileID  uint64
+	Content int
+}
+
+// pmtilesContent is a unique tile content in the temporary data file
+type pmtilesContent struct {
+	Offset uint64
+	Length uint32
+}
+
+// pmtilesWriter writes all tiles into a single clustered PMTiles v3 archive
+//
+// Tiles are appended to a temporary file while they are written (identical tiles
+// are only stored once). Close sorts the tiles by their tile id and copies them
+// into the archive, which results in a clustered archive.
+type pmtilesWriter struct {
+	filePath   string
+	tileFormat string
+
+	tmpFile    *os.File
+	tmpOffset  uint64
+	tiles      []pmtilesTile
+	contents   []pmtilesContent
+	contentIDs map[[sha1.Size]byte]int
+	mux        sync.Mutex
+}
+
+func newPMTilesWriter(filePath string, tileFormat string) (*pmtilesWriter, error) {
+	// make sure the directory of the output file exists
+	if !utils.IsDirectory(path.Dir(filePath)) {
+		return nil, errors.New("Directory of output file doesn't exists")
+	}
+
+	if utils.IsDirectory(filePath) {
+		return nil, fmt.Errorf("%s is a directory, but the pmtiles format needs a file path", filePath)
+	}
+
+	tmpFile, err := os.CreateTemp(path.Dir(filePath), ".pmtiles-*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &pmtilesWriter{
+		filePath:   filePath,
+		tileFormat: tileFormat,
+		tmpFile:    tmpFile,
+		contentIDs: make(map[[sha1.Size]byte]int),
+	}, nil
+}
+
+func (w *pmtilesWriter) WriteTile(lod uint8, col uint32, row uint32, data []byte) error {
+	hash := sha1.Sum(data)
+
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	contentID, found := w.contentIDs[hash]
+	if !found {
+		_, err := w.tmpFile.Write(data)
+		if err != nil {
+			return err
+		}
+
+		contentID = len(w.contents)
+		w.contents = append(w.contents, pmtilesContent{Offset: w.tmpOffset, Length: uint32(len(data))})
+		w.contentIDs[hash] = contentID
+		w.tmpOffset += uint64(len(data))
+	}
+
+	w.tiles = append(w.tiles, pmtilesTile{TileID: zxyToTileID(lod, col, row), Content: contentID})
+
+	return nil
+}
+
+func (w *pmtilesWriter) Close(metadata tilejson.TileJSON) error {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	defer os.Remove(w.tmpFile.Name())
+	defer w.tmpFile.Close()
+
+	sort.Slice(w.tiles, func(i, j int) bool { return w.tiles[i].TileID < w.tiles[j].TileID })
+
+	// assign final offsets in the order of the tile ids (= clustered)
+	finalOffsets := make([]uint64, len(w.contents))
+	placed := make([]bool, len(w.contents))
+	order := make([]int, 0, len(w.contents))
+	tileDataLength := uint64(0)
+
+	entries := make([]pmtilesEntry, 0, len(w.tiles))
+	for _, tile := range w.tiles {
+		content := w.contents[tile.Content]
+
+		if !placed[tile.Content] {
+			placed[tile.Content] = true
+			finalOffsets[tile.Content] = tileDataLength
+			order = append(order, tile.Content)
+			tileDataLength += uint64(content.Length)
+		}
+
+		offset := finalOffsets[tile.Content]
+
+		// extend run of previous entry, if this is the same content as the previous tile
+		if len(entries) > 0 {
+			last := &entries[len(entries)-1]
+			if last.Offset == offset && last.TileID+uint64(last.RunLength) == tile.TileID {
+				last.RunLength++
+				continue
+			}
+		}
+
+		entries = append(entries, pmtilesEntry{TileID: tile.TileID, Offset: offset, Length: content.Length, RunLength: 1})
+	}
+
+	rootDir, leafDirs, err := buildPMTilesDirectories(entries)
+	if err != nil {
+		return err
+	}
+
+	metadataBytes, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	metadataBytes, err = gzipBytes(metadataBytes)
+	if err != nil {
+		return err
+	}
+
+	header := pmtilesHeader{
+		RootOffset:          pmtilesHeaderLength,
+		RootLength:          uint64(len(rootDir)),
+		MetadataLength:      uint64(len(metadataBytes)),
+		LeafDirsLength:      uint64(len(leafDirs)),
+		TileDataLength:      tileDataLength,
+		AddressedTilesCount: uint64(len(w.tiles)),
+		TileEntriesCount:    uint64(len(entries)),
+		TileContentsCount:   uint64(len(w.contents)),
+		MinZoom:             metadata.Minzoom,
+		MaxZoom:             metadata.Maxzoom,
+		CenterZoom:          metadata.Minzoom,
+		TileCompression:     pmtilesCompressionNone,
+		TileType:            pmtilesTileTypePng,
+	}
+	header.MetadataOffset = header.RootOffset + header.RootLength
+	header.LeafDirsOffset = header.MetadataOffset + header.MetadataLength
+	header.TileDataOffset = header.LeafDirsOffset + header.LeafDirsLength
+
+	// vector tiles are gzipped by mvt.MarshalGzipped
+	if w.tileFormat == "pbf" {
+		header.TileCompression = pmtilesCompressionGzip
+		header.TileType = pmtilesTileTypeMvt
+	}
+
+	// write archive
+	f, err := os.Create(w.filePath)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(f)
+
+	for _, section := range [][]byte{header.serialize(), rootDir, metadataBytes, leafDirs} {
+		_, err = out.Write(section)
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	// copy tile data from the temporary file in clustered order
+	for _, contentID := range order {
+		content := w.contents[contentID]
+
+		_, err = io.Copy(out, io.NewSectionReader(w.tmpFile, int64(content.Offset), int64(content.Length)))
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	err = out.Flush()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// buildPMTilesDirectories serializes the root directory and, if the entries don't fit into
+// the root directory, the leaf directories
+func buildPMTilesDirectories(entries []pmtilesEntry) ([]byte, []byte, error) {
+	rootDir, err := serializePMTilesDirectory(entries)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(rootDir) <= pmtilesMaxRootLength {
+		return rootDir, []byte{}, nil
+	}
+
+	// increase the leaf size until the root directory fits into the first 16 KiB
+	leafSize := float64(4096)
+	for {
+		rootEntries := []pmtilesEntry{}
+		leafDirs := []byte{}
+
+		for start := 0; start < len(entries); start += int(leafSize) {
+			end := start + int(leafSize)
+			if end > len(entries) {
+				end = len(entries)
+			}
+
+			leaf, err := serializePMTilesDirectory(entries[start:end])
+			if err != nil {
+				return nil, nil, err
+			}
+
+			// a run length of 0 marks the entry as a leaf directory
+			rootEntries = append(rootEntries, pmtilesEntry{
+				TileID:    entries[start].TileID,
+				Offset:    uint64(len(leafDirs)),
+				Length:    uint32(len(leaf)),
+				RunLength: 0,
+			})
+			leafDirs = append(leafDirs, leaf...)
+		}
+
+		rootDir, err = serializePMTilesDirectory(rootEntries)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(rootDir) <= pmtilesMaxRootLength {
+			return rootDir, leafDirs, nil
+		}
+
+		leafSize *= 1.2
+	}
+}
+
+// serializePMTilesDirectory serializes and gzips a directory
+func serializePMTilesDirectory(entries []pmtilesEntry) ([]byte, error) {
+	buf := make([]byte, 0, len(entries)*8)
+	varint := make([]byte, binary.MaxVarintLen64)
+
+	putUvarint := func(v uint64) {
+		n := binary.PutUvarint(varint, v)
+		buf = append(buf, varint[:n]...)
+	}
+
+	putUvarint(uint64(len(entries)))
+
+	lastID := uint64(0)
+	for _, e := range entries {
+		putUvarint(e.TileID - lastID)
+		lastID = e.TileID
+	}
+
+	for _, e := range entries {
+		putUvarint(uint64(e.RunLength))
+	}
+
+	for _, e := range entries {
+		putUvarint(uint64(e.Length))
+	}
+
+	for i, e := range entries {
+		// an offset of 0 means the data directly follows the data of the previous entry
+		if i > 0 && e.Offset == entries[i-1].Offset+uint64(entries[i-1].Length) {
+			putUvarint(0)
+		} else {
+			putUvarint(e.Offset + 1)
+		}
+	}
+
+	return gzipBytes(buf)
+}
+
+// pmtilesHeader represents the fixed size header of a PMTiles v3 archive
+type pmtilesHeader struct {
+	RootOffset          uint64
+	RootLength          uint64
+	MetadataOffset      uint64
+	MetadataLength      uint64
+	LeafDirsOffset      uint64
+	LeafDirsLength      uint64
+	TileDataOffset      uint64
+	TileDataLength      uint64
+	AddressedTilesCount uint64
+	TileEntriesCount    uint64
+	TileContentsCount   uint64
+	TileCompression     uint8
+	TileType            uint8
+	MinZoom             uint8
+	MaxZoom             uint8
+	CenterZoom          uint8
+}
+
+func (h pmtilesHeader) serialize() []byte {
+	b := make([]byte, pmtilesHeaderLength)
+
+	copy(b[0:7], "PMTiles")
+	b[7] = 3
+
+	binary.LittleEndian.PutUint64(b[8:], h.RootOffset)
+	binary.LittleEndian.PutUint64(b[16:], h.RootLength)
+	binary.LittleEndian.PutUint64(b[24:], h.MetadataOffset)
+	binary.LittleEndian.PutUint64(b[32:], h.MetadataLength)
+	binary.LittleEndian.PutUint64(b[40:], h.LeafDirsOffset)
+	binary.LittleEndian.PutUint64(b[48:], h.LeafDirsLength)
+	binary.LittleEndian.PutUint64(b[56:], h.TileDataOffset)
+	binary.LittleEndian.PutUint64(b[64:], h.TileDataLength)
+	binary.LittleEndian.PutUint64(b[72:], h.AddressedTilesCount)
+	binary.LittleEndian.PutUint64(b[80:], h.TileEntriesCount)
+	binary.LittleEndian.PutUint64(b[88:], h.TileContentsCount)
+
+	b[96] = 1 // clustered
+	b[97] = pmtilesCompressionGzip
+	b[98] = h.TileCompression
+	b[99] = h.TileType
+	b[100] = h.MinZoom
+	b[101] = h.MaxZoom
+
+	// Arma maps cover the whole web mercator square
+	binary.LittleEndian.PutUint32(b[102:], e7(-180))
+	binary.LittleEndian.PutUint32(b[106:], e7(-85.0511))
+	binary.LittleEndian.PutUint32(b[110:], e7(180))
+	binary.LittleEndian.PutUint32(b[114:], e7(85.0511))
+
+	b[118] = h.CenterZoom
+	binary.LittleEndian.PutUint32(b[119:], 0)
+	binary.LittleEndian.PutUint32(b[123:], 0)
+
+	return b
+}
+
+// e7 converts a coordinate to the fixed point representation used in the header
+func e7(deg float64) uint32 {
+	return uint32(int32(deg * 10000000))
+}
+
+// zxyToTileID converts tile coordinates to a PMTiles tile id, which is
+// the position of the tile on a hilbert curve over all zoom levels
+func zxyToTileID(z uint8, x uint32, y uint32) uint64 {
+	id := uint64(0)
+
+	// skip all tiles of the lower zoom levels
+	for i := uint8(0); i < z; i++ {
+		id += uint64(1) << (2 * i)
+	}
+
+	n := uint32(1) << z
+	for s := n / 2; s > 0; s /= 2 {
+		rx := uint32(0)
+		if x&s > 0 {
+			rx = 1
+		}
+		ry := uint32(0)
+		if y&s > 0 {
+			ry = 1
+		}
+
+		id += uint64(s) * uint64(s) * uint64((3*rx)^ry)
+
+		// rotate quadrant
+		if ry == 0 {
+			if rx == 1 {
+				x = s - 1 - x%s
+				y = s - 1 - y%s
+			}
+			x, y = y, x
+		}
+	}
+
+	return id
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	gz := gzip.NewWriter(&buf)
+
+	_, err := gz.Write(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = gz.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,43 @@
+package tilewriter
+
+import (
+	"fmt"
+
+	"github.com/gruppe-adler/meh-utils/internal/tilejson"
+)
+
+const (
+	// Directory writes every tile to {lod}/{col}/{row}.{ext}
+	Directory = "directory"
+	// MBTiles writes all tiles into a single MBTiles SQLite file
+	MBTiles = "mbtiles"
+	// PMTiles writes all tiles into a single PMTiles v3 archive
+	PMTiles = "pmtiles"
+)
+
+// Formats lists all supported output formats
+var Formats = []string{Directory, MBTiles, PMTiles}
+
+// Writer writes the tiles of a tileset
+type Writer interface {
+	// WriteTile writes the data of the tile at lod/col/row. It is safe for concurrent use.
+	WriteTile(lod uint8, col uint32, row uint32, data []byte) error
+
+	// Close writes the metadata of the tileset and finalizes the output
+	Close(metadata tilejson.TileJSON) error
+}
+
+// New creates a Writer for given output format. tileFormat is the file
+// extension of the tiles ("png" or "pbf").
+func New(format string, outputPath string, tileFormat string) (Writer, error) {
+	switch format {
+	case "", Directory:
+		return newDirectoryWriter(outputPath, tileFormat)
+	case MBTiles:
+		return newMBTilesWriter(outputPath, tileFormat)
+	case PMTiles:
+		return newPMTilesWriter(outputPath, tileFormat)
+	}
+
+	return nil, fmt.Errorf("Unknown output format: %s", format)
+}
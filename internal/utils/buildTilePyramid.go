@@ -0,0 +1,269 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"runtime"
+	"sync"
+
+	"github.com/nfnt/resize"
+	"golang.org/x/sync/semaphore"
+)
+
+// TileWriter writes the data of a single tile
+type TileWriter interface {
+	WriteTile(lod uint8, col uint32, row uint32, data []byte) error
+}
+
+// ImageSource provides the pixels a tile pyramid is built from. *image.RGBA implements
+// ImageSource, but it can also be implemented by sources which never hold the whole image.
+type ImageSource interface {
+	Bounds() image.Rectangle
+	SubImage(r image.Rectangle) image.Image
+}
+
+// Resampler defines how pixels are resampled while building a tile pyramid
+type Resampler interface {
+	// Resize scales given image to the size of a tile
+	Resize(img image.Image) *image.RGBA
+
+	// Downsample merges four tiles (top left, top right, bottom left, bottom right)
+	// of a LOD into one tile of the next lower LOD
+	Downsample(children [4]*image.RGBA) *image.RGBA
+}
+
+// RGBAResampler resizes with a Mitchell-Netravali filter and downsamples by averaging 2x2 pixels
+type RGBAResampler struct{}
+
+// Resize scales given image to the size of a tile
+func (RGBAResampler) Resize(img image.Image) *image.RGBA {
+	return ToRGBA(resize.Resize(TileSizeInPx, TileSizeInPx, img, resize.MitchellNetravali))
+}
+
+// ToRGBA converts given image to an *image.RGBA, if it isn't one already
+func ToRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	return rgba
+}
+
+// Downsample merges four tiles into one tile by averaging each 2x2 block of pixels
+func (RGBAResampler) Downsample(children [4]*image.RGBA) *image.RGBA {
+	tile := image.NewRGBA(image.Rect(0, 0, TileSizeInPx, TileSizeInPx))
+
+	DownsampleQuadrants(tile, children, func(pixels [4][]uint8, dst []uint8) {
+		// image.RGBA is alpha-premultiplied, so we can just average all channels
+		for c := 0; c < 4; c++ {
+			sum := uint32(pixels[0][c]) + uint32(pixels[1][c]) + uint32(pixels[2][c]) + uint32(pixels[3][c])
+			dst[c] = uint8((sum + 2) / 4)
+		}
+	})
+
+	return tile
+}
+
+// NearestResampler resizes with nearest-neighbor interpolation and downsamples by a majority vote
+// of 2x2 pixels, so the tiles only contain colors of the source (e.g. the colors of classes)
+type NearestResampler struct{}
+
+// Resize scales given image to the size of a tile
+func (NearestResampler) Resize(img image.Image) *image.RGBA {
+	return ToRGBA(resize.Resize(TileSizeInPx, TileSizeInPx, img, resize.NearestNeighbor))
+}
+
+// Downsample merges four tiles into one tile by using the most common color of each 2x2 block of pixels
+func (NearestResampler) Downsample(children [4]*image.RGBA) *image.RGBA {
+	tile := image.NewRGBA(image.Rect(0, 0, TileSizeInPx, TileSizeInPx))
+
+	DownsampleQuadrants(tile, children, func(pixels [4][]uint8, dst []uint8) {
+		// on a tie the first of the most common colors wins
+		winner, winnerVotes := 0, 0
+		for i := range pixels {
+			votes := 0
+			for j := range pixels {
+				if bytes.Equal(pixels[i], pixels[j]) {
+					votes++
+				}
+			}
+			if votes > winnerVotes {
+				winner, winnerVotes = i, votes
+			}
+		}
+
+		copy(dst, pixels[winner])
+	})
+
+	return tile
+}
+
+// DownsampleQuadrants calls merge for every pixel of tile with the 2x2 pixels
+// (top left, top right, bottom left, bottom right) of the child tile covering it
+func DownsampleQuadrants(tile *image.RGBA, children [4]*image.RGBA, merge func(pixels [4][]uint8, dst []uint8)) {
+	half := TileSizeInPx / 2
+
+	for i, child := range children {
+		offsetX := (i % 2) * half
+		offsetY := (i / 2) * half
+		bounds := child.Bounds()
+
+		for y := 0; y < half; y++ {
+			for x := 0; x < half; x++ {
+				srcX := bounds.Min.X + 2*x
+				srcY := bounds.Min.Y + 2*y
+
+				tl := child.PixOffset(srcX, srcY)
+				tr := child.PixOffset(srcX+1, srcY)
+				bl := child.PixOffset(srcX, srcY+1)
+				br := child.PixOffset(srcX+1, srcY+1)
+
+				dst := tile.PixOffset(offsetX+x, offsetY+y)
+
+				merge(
+					[4][]uint8{child.Pix[tl : tl+4], child.Pix[tr : tr+4], child.Pix[bl : bl+4], child.Pix[br : br+4]},
+					tile.Pix[dst:dst+4],
+				)
+			}
+		}
+	}
+}
+
+// tilePyramid holds everything needed to build a tile pyramid
+type tilePyramid struct {
+	maxLod      uint8
+	parallelLod uint8
+	src         ImageSource
+	resampler   Resampler
+	writer      TileWriter
+	sem         *semaphore.Weighted
+}
+
+// BuildTilePyramid builds all tiles from LOD 0 to maxLod from given source and writes them with given writer.
+//
+// Only the tiles of maxLod are resized from the source. Every tile of a lower LOD is
+// downsampled from its four child tiles. The pyramid is built depth first and only the
+// lowest levels of each subtree are built in parallel, so just a few tiles have to be kept
+// in memory and all tiles which are built at the same time cover a small area of the source.
+func BuildTilePyramid(maxLod uint8, src ImageSource, resampler Resampler, writer TileWriter) {
+	p := tilePyramid{
+		maxLod:      maxLod,
+		parallelLod: maxLod,
+		src:         src,
+		resampler:   resampler,
+		writer:      writer,
+		sem:         semaphore.NewWeighted(int64(runtime.NumCPU())),
+	}
+
+	// children of tiles at or below parallelLod are built in parallel
+	for p.parallelLod > 0 && 1<<(2*(maxLod-p.parallelLod)) < runtime.NumCPU() {
+		p.parallelLod--
+	}
+
+	p.buildTile(0, 0, 0)
+}
+
+// buildTile builds and writes the tile lod/col/row and returns its image
+func (p *tilePyramid) buildTile(lod uint8, col uint32, row uint32) *image.RGBA {
+	var tile *image.RGBA
+
+	if lod == p.maxLod {
+		p.sem.Acquire(context.Background(), 1)
+		tile = p.resampler.Resize(p.src.SubImage(p.tileRect(col, row)))
+		p.sem.Release(1)
+	} else {
+		var children [4]*image.RGBA
+
+		if lod >= p.parallelLod {
+			wg := sync.WaitGroup{}
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					children[i] = p.buildTile(lod+1, 2*col+uint32(i%2), 2*row+uint32(i/2))
+				}(i)
+			}
+			wg.Wait()
+		} else {
+			for i := 0; i < 4; i++ {
+				children[i] = p.buildTile(lod+1, 2*col+uint32(i%2), 2*row+uint32(i/2))
+			}
+		}
+
+		p.sem.Acquire(context.Background(), 1)
+		tile = p.resampler.Downsample(children)
+		p.sem.Release(1)
+	}
+
+	p.sem.Acquire(context.Background(), 1)
+	data, err := encodeTile(tile)
+	p.sem.Release(1)
+	if err != nil {
+		fmt.Printf("Error while encoding tile %d/%d/%d: %s\n", lod, col, row, err)
+		return tile
+	}
+
+	err = p.writer.WriteTile(lod, col, row, data)
+	if err != nil {
+		fmt.Printf("Error while writing tile %d/%d/%d: %s\n", lod, col, row, err)
+	}
+
+	return tile
+}
+
+// tileRect calculates the rectangle of the source, which is covered by given tile of the max LOD
+func (p *tilePyramid) tileRect(col uint32, row uint32) image.Rectangle {
+	tilesPerRowCol := 1 << p.maxLod
+
+	width := p.src.Bounds().Dx()
+	height := p.src.Bounds().Dy()
+
+	tileWidth := width / tilesPerRowCol
+	tileHeight := height / tilesPerRowCol
+
+	// remaining pixels
+	widthRemainder := width % tilesPerRowCol
+	heightRemainder := height % tilesPerRowCol
+
+	// if we have any remaining pixels we'll distrubute them to the first rows / cols
+	x := tileWidth*int(col) + minInt(int(col), widthRemainder)
+	y := tileHeight*int(row) + minInt(int(row), heightRemainder)
+	w := tileWidth
+	h := tileHeight
+	if int(col) < widthRemainder {
+		w++
+	}
+	if int(row) < heightRemainder {
+		h++
+	}
+
+	p1 := p.src.Bounds().Min.Add(image.Point{x, y})
+
+	return image.Rectangle{p1, p1.Add(image.Point{w, h})}
+}
+
+// encodeTile encodes given tile as PNG
+func encodeTile(tile *image.RGBA) ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := png.Encode(&buf, tile)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
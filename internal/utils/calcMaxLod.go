@@ -1,17 +1,17 @@
 package utils
 
 import (
-	"image"
 	"math"
 )
 
-const tileSizeInPx = 256
+// TileSizeInPx is the width and height of a raster tile
+const TileSizeInPx = 256
 
-// CalcMaxLodFromImage calculates maximum LOD based on the width of the combinedSatImage
-func CalcMaxLodFromImage(image *image.RGBA) uint8 {
-	w := float64(image.Bounds().Dy())
+// CalcMaxLodFromImage calculates maximum LOD based on the larger dimension of the combinedSatImage
+func CalcMaxLodFromImage(image ImageSource) uint8 {
+	w := math.Max(float64(image.Bounds().Dx()), float64(image.Bounds().Dy()))
 
-	tilesPerRowCol := math.Ceil(w / tileSizeInPx)
+	tilesPerRowCol := math.Ceil(w / TileSizeInPx)
 
 	return uint8(math.Ceil(math.Log2(tilesPerRowCol)))
 }
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/paulmach/orb"
+)
+
+// ParseCoordinates reads one coordinate per line ("x,y" or "x y"). Empty lines and lines
+// starting with # are skipped.
+func ParseCoordinates(reader io.Reader) ([]orb.Point, error) {
+	points := []orb.Point{}
+
+	err := ScanCoordinates(reader, func(p orb.Point) error {
+		points = append(points, p)
+		return nil
+	})
+
+	return points, err
+}
+
+// ScanCoordinates reads one coordinate per line like ParseCoordinates, but calls fn for
+// each coordinate as soon as its line is read
+func ScanCoordinates(reader io.Reader, fn func(p orb.Point) error) error {
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p, err := ParseCoordinate(line)
+		if err != nil {
+			return fmt.Errorf("Line %d: %s", lineNumber, err)
+		}
+
+		err = fn(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// ParseCoordinate parses a coordinate in the form "x,y" or "x y"
+func ParseCoordinate(s string) (orb.Point, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t'
+	})
+
+	if len(fields) != 2 {
+		return orb.Point{}, fmt.Errorf("Expected x,y: %s", s)
+	}
+
+	return ParseCoordinateFields(fields)
+}
+
+// ParseCoordinateFields parses x and y from the first two fields, e.g. the columns of a CSV row
+func ParseCoordinateFields(fields []string) (orb.Point, error) {
+	if len(fields) < 2 {
+		return orb.Point{}, fmt.Errorf("Expected x and y")
+	}
+
+	x, err := strconv.ParseFloat(strings.TrimSpace(fields[0]), 64)
+	if err != nil {
+		return orb.Point{}, fmt.Errorf("Invalid x: %s", fields[0])
+	}
+
+	y, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+	if err != nil {
+		return orb.Point{}, fmt.Errorf("Invalid y: %s", fields[1])
+	}
+
+	return orb.Point{x, y}, nil
+}
@@ -9,13 +9,19 @@ import (
 
 // MehDirectory validates that given directory is valid grad_meh directory
 func MehDirectory(mehDirPath string) error {
+	return MehDirectoryWithDEM(mehDirPath, path.Join(mehDirPath, "dem.asc.gz"))
+}
+
+// MehDirectoryWithDEM validates that given directory is valid grad_meh directory,
+// whose DEM is replaced by the DEM at demPath
+func MehDirectoryWithDEM(mehDirPath string, demPath string) error {
 	if !utils.IsDirectory(mehDirPath) {
 		return fmt.Errorf("%s does not exists or is no directory", mehDirPath)
 	}
 
 	// check DEM
-	if !utils.IsFile(path.Join(mehDirPath, "dem.asc.gz")) {
-		return fmt.Errorf("%s is missing", path.Join(mehDirPath, "dem.asc.gz"))
+	if !utils.IsFile(demPath) {
+		return fmt.Errorf("%s is missing", demPath)
 	}
 
 	// check preview.png
@@ -38,21 +44,53 @@ func MehDirectory(mehDirPath string) error {
 
 // SatDirectory validates that given directory is valid grad_meh sat directory
 func SatDirectory(satDirPath string) error {
+	_, _, err := SatGrid(satDirPath)
+
+	return err
+}
+
+// SatGrid finds the number of columns and rows of the sat tiles in given grad_meh sat
+// directory. The tiles are expected at {col}/{row}.png and every column must have the
+// same number of rows.
+func SatGrid(satDirPath string) (cols int, rows int, err error) {
 
 	// check if directory exists
 	if !utils.IsDirectory(satDirPath) {
-		return fmt.Errorf("%s does not exists or is no directory", satDirPath)
+		return 0, 0, fmt.Errorf("%s does not exists or is no directory", satDirPath)
+	}
+
+	// count columns
+	for utils.IsDirectory(path.Join(satDirPath, fmt.Sprintf("%d", cols))) {
+		cols++
 	}
 
-	// check if sat tiles exist
-	for col := 0; col < 4; col++ {
-		for row := 0; row < 4; row++ {
+	if cols == 0 {
+		return 0, 0, fmt.Errorf("%s is missing", path.Join(satDirPath, "0"))
+	}
+
+	// count rows of first column
+	for utils.IsFile(path.Join(satDirPath, "0", fmt.Sprintf("%d.png", rows))) {
+		rows++
+	}
+
+	if rows == 0 {
+		return 0, 0, fmt.Errorf("%s is missing", path.Join(satDirPath, "0", "0.png"))
+	}
+
+	// check that all columns have the same rows
+	for col := 1; col < cols; col++ {
+		for row := 0; row < rows; row++ {
 			filePath := path.Join(satDirPath, fmt.Sprintf("%d", col), fmt.Sprintf("%d.png", row))
 			if !utils.IsFile(filePath) {
-				return fmt.Errorf("%s is missing", filePath)
+				return 0, 0, fmt.Errorf("%s is missing", filePath)
 			}
 		}
+
+		filePath := path.Join(satDirPath, fmt.Sprintf("%d", col), fmt.Sprintf("%d.png", rows))
+		if utils.IsFile(filePath) {
+			return 0, 0, fmt.Errorf("%s has more rows than column 0", path.Join(satDirPath, fmt.Sprintf("%d", col)))
+		}
 	}
 
-	return nil
+	return cols, rows, nil
 }
@@ -0,0 +1,27 @@
+package viewshed
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+)
+
+// visibleColor is the color of visible cells. All other cells are transparent.
+var visibleColor = color.NRGBA{0, 200, 0, 160}
+
+func calculateImage(raster dem.EsriASCIIRaster, visible []bool) *image.RGBA {
+	w, h := raster.Dims()
+
+	img := image.NewRGBA(image.Rectangle{image.Point{0, 0}, image.Point{int(w), int(h)}})
+
+	for col := uint(0); col < w; col++ {
+		for row := uint(0); row < h; row++ {
+			if visible[row*w+col] {
+				img.Set(int(col), int(row), visibleColor)
+			}
+		}
+	}
+
+	return img
+}
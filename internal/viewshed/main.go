@@ -0,0 +1,200 @@
+package viewshed
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+	"github.com/gruppe-adler/meh-utils/internal/metajson"
+	"github.com/gruppe-adler/meh-utils/internal/tilejson"
+	"github.com/gruppe-adler/meh-utils/internal/tilewriter"
+	"github.com/gruppe-adler/meh-utils/internal/utils"
+	"github.com/gruppe-adler/meh-utils/internal/validate"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geojson"
+	"golang.org/x/sync/semaphore"
+)
+
+// Run is the program's entrypoint
+func Run(flagSet *flag.FlagSet) {
+
+	var timer time.Time
+	start := time.Now()
+
+	outputPtr := flagSet.String("out", "", "Path to output directory (or output file for -format mbtiles / pmtiles)")
+	inputPtr := flagSet.String("in", "", "Path to grad_meh map directory")
+	formatPtr := flagSet.String("format", tilewriter.Directory, fmt.Sprintf("Output format (%s)", strings.Join(tilewriter.Formats, ", ")))
+	demPtr := flagSet.String("dem", "", "Path to DEM (ESRI ASCII grid, XYZ, GeoTIFF or ESRI float grid), defaults to the dem.asc.gz of the grad_meh map directory")
+	observersPtr := flagSet.String("observers", "", "Path to file with one observer position (x,y) per line. Positions can also be passed as arguments.")
+	eyeHeightPtr := flagSet.Float64("eye_height", 1.7, "Height of the observers' eyes above the ground in meters")
+	targetHeightPtr := flagSet.Float64("target_height", 0, "Height above the ground in meters, which has to be visible")
+	rangePtr := flagSet.Float64("range", 2000, "Maximum distance from an observer in meters")
+	geoJSONPtr := flagSet.String("geojson", "", "Path to output GeoJSON file with the visible area as polygons (optional)")
+
+	flagSet.Parse(os.Args[2:])
+
+	// make sure both flags are present
+	if *outputPtr == "" || *inputPtr == "" {
+		flagSet.PrintDefaults()
+		os.Exit(1)
+	}
+
+	demPath := *demPtr
+	if demPath == "" {
+		demPath = path.Join(*inputPtr, "dem.asc.gz")
+	}
+
+	// read observers
+	observers := []orb.Point{}
+	if *observersPtr != "" {
+		file, err := os.Open(*observersPtr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		observers, err = utils.ParseCoordinates(file)
+		file.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	for _, arg := range flagSet.Args() {
+		p, err := utils.ParseCoordinate(arg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		observers = append(observers, p)
+	}
+	if len(observers) == 0 {
+		log.Fatal(errors.New("No observer positions"))
+	}
+
+	// create tile writer (makes sure the output path is valid)
+	writer, err := tilewriter.New(*formatPtr, *outputPtr, "png")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// validate input directory structure
+	err = validate.MehDirectoryWithDEM(*inputPtr, demPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println("✔️  Validated input directory structure")
+
+	// load meta.json
+	timer = time.Now()
+	fmt.Println("▶️  Loading meta.json")
+	meta, err := metajson.Read(path.Join(*inputPtr, "meta.json"))
+	if err != nil {
+		log.Fatal(errors.New("Failed to read meta.json"))
+	}
+	fmt.Println("✔️  Loaded meta.json in", time.Now().Sub(timer).String())
+
+	// load DEM
+	timer = time.Now()
+	fmt.Println("▶️  Loading DEM")
+	raster := dem.Read(demPath)
+	fmt.Println("✔️  Loaded DEM in", time.Now().Sub(timer).String())
+
+	// calculate viewshed
+	timer = time.Now()
+	fmt.Printf("▶️  Calculating viewshed of %d observers\n", len(observers))
+	visible := calculateViewshed(raster, observers, *eyeHeightPtr, *targetHeightPtr, *rangePtr)
+	fmt.Println("✔️  Calculated viewshed in", time.Now().Sub(timer).String())
+
+	// write polygons
+	if *geoJSONPtr != "" {
+		timer = time.Now()
+		fmt.Println("▶️  Writing viewshed polygons")
+		err = writePolygons(*geoJSONPtr, polygonize(raster, visible))
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("✔️  Wrote viewshed polygons in", time.Now().Sub(timer).String())
+	}
+
+	// calculating image
+	img := calculateImage(raster, visible)
+
+	// calculate max LOD
+	maxLod := utils.CalcMaxLodFromImage(img)
+	fmt.Println("ℹ️  Calculated max lod:", maxLod)
+
+	// build tiles
+	timer = time.Now()
+	fmt.Println("▶️  Building tiles")
+	utils.BuildTilePyramid(maxLod, img, utils.RGBAResampler{}, writer)
+	fmt.Println("✔️  Built viewshed tiles in", time.Now().Sub(timer).String())
+
+	// write tile.json / metadata
+	timer = time.Now()
+	fmt.Println("▶️  Writing tileset metadata")
+	err = writer.Close(tilejson.New(maxLod, meta, "Viewshed", []string{}))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("✔️  Wrote tileset metadata in", time.Now().Sub(timer).String())
+
+	fmt.Printf("\n    🎉  Finished in %s\n", time.Now().Sub(start).String())
+}
+
+// calculateViewshed combines the viewsheds of all observers, which are calculated concurrently
+func calculateViewshed(raster dem.EsriASCIIRaster, observers []orb.Point, eyeHeight, targetHeight, maxRange float64) []bool {
+	visible := make([]bool, raster.Ncols*raster.Nrows)
+	var visibleMux sync.Mutex
+
+	waitGrp := sync.WaitGroup{}
+	sem := semaphore.NewWeighted(int64(runtime.NumCPU()))
+	for _, observer := range observers {
+		waitGrp.Add(1)
+		sem.Acquire(context.Background(), 1)
+		go func(observer orb.Point) {
+			defer waitGrp.Done()
+			defer sem.Release(1)
+
+			v, ok := raster.Viewshed(observer[0], observer[1], eyeHeight, targetHeight, maxRange)
+			if !ok {
+				fmt.Printf("⚠️  Observer at %.0f, %.0f is outside of the DEM\n", observer[0], observer[1])
+				return
+			}
+
+			// only the window of the viewshed can be visible
+			visibleMux.Lock()
+			for r := uint(0); r < v.Nrows; r++ {
+				offset := (v.Row+r)*raster.Ncols + v.Col
+				for c, vis := range v.Visible[r*v.Ncols : (r+1)*v.Ncols] {
+					visible[offset+uint(c)] = visible[offset+uint(c)] || vis
+				}
+			}
+			visibleMux.Unlock()
+		}(observer)
+	}
+	waitGrp.Wait()
+
+	return visible
+}
+
+// writePolygons writes the visible area as GeoJSON
+func writePolygons(filePath string, polygons orb.MultiPolygon) error {
+	fc := geojson.NewFeatureCollection()
+	for _, polygon := range polygons {
+		fc.Append(geojson.NewFeature(polygon))
+	}
+
+	bytes, err := fc.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filePath, bytes, 0644)
+}
@@ -0,0 +1,214 @@
+package viewshed
+
+import (
+	"github.com/gruppe-adler/meh-utils/internal/dem"
+	"github.com/paulmach/orb"
+)
+
+// edge is a side of a visible cell, which borders a cell that isn't visible. It is directed so
+// that the visible cell is on its left (in world coordinates, where y points up).
+type edge struct {
+	from, to  int
+	component int32
+}
+
+// polygonize traces the outlines of the visible cells. Every cell is the square around the
+// position of its grid value and every 4-connected group of visible cells becomes a polygon.
+// Outer rings are counter-clockwise and holes clockwise.
+func polygonize(raster dem.EsriASCIIRaster, visible []bool) orb.MultiPolygon {
+	// only look at the bounding box of the visible cells
+	minC, minR, maxC, maxR := int(raster.Ncols), int(raster.Nrows), -1, -1
+	for i, v := range visible {
+		if !v {
+			continue
+		}
+		c, r := i%int(raster.Ncols), i/int(raster.Ncols)
+		minC, minR = minInt(minC, c), minInt(minR, r)
+		maxC, maxR = maxInt(maxC, c), maxInt(maxR, r)
+	}
+	if maxC < 0 {
+		return orb.MultiPolygon{}
+	}
+
+	w, h := maxC-minC+1, maxR-minR+1
+	isVisible := func(c, r int) bool {
+		if c < 0 || r < 0 || c >= w || r >= h {
+			return false
+		}
+		return visible[(minR+r)*int(raster.Ncols)+minC+c]
+	}
+
+	components := labelComponents(w, h, isVisible)
+
+	// vertices are the corners of the cells
+	vertex := func(i, j int) int { return j*(w+1) + i }
+
+	edges := []edge{}
+	outgoing := make(map[int][]int)
+	addEdge := func(from, to int, component int32) {
+		outgoing[from] = append(outgoing[from], len(edges))
+		edges = append(edges, edge{from, to, component})
+	}
+
+	for r := 0; r < h; r++ {
+		for c := 0; c < w; c++ {
+			if !isVisible(c, r) {
+				continue
+			}
+			component := components[r*w+c]
+
+			if !isVisible(c, r+1) {
+				addEdge(vertex(c, r+1), vertex(c+1, r+1), component)
+			}
+			if !isVisible(c+1, r) {
+				addEdge(vertex(c+1, r+1), vertex(c+1, r), component)
+			}
+			if !isVisible(c, r-1) {
+				addEdge(vertex(c+1, r), vertex(c, r), component)
+			}
+			if !isVisible(c-1, r) {
+				addEdge(vertex(c, r), vertex(c, r+1), component)
+			}
+		}
+	}
+
+	direction := func(e edge) (int, int) {
+		return e.to%(w+1) - e.from%(w+1), e.to/(w+1) - e.from/(w+1)
+	}
+
+	toPoint := func(v int) orb.Point {
+		i, j := v%(w+1), v/(w+1)
+		return orb.Point{
+			raster.X(0) + (float64(minC+i)-0.5)*raster.CellSize,
+			raster.Y(0) - (float64(minR+j)-0.5)*raster.CellSize,
+		}
+	}
+
+	// trace rings by following the edges. Where two visible cells only touch at a corner, the ring
+	// turns left, so that it stays at the cell it came from and the cells end up in separate rings.
+	used := make([]bool, len(edges))
+	outers := make(map[int32]orb.Ring)
+	holes := make(map[int32][]orb.Ring)
+
+	for start := range edges {
+		if used[start] {
+			continue
+		}
+
+		ring := orb.Ring{}
+		current := start
+		for {
+			used[current] = true
+			ring = append(ring, toPoint(edges[current].from))
+
+			next := outgoing[edges[current].to][0]
+			if candidates := outgoing[edges[current].to]; len(candidates) > 1 {
+				dc, dr := direction(edges[current])
+				for _, candidate := range candidates {
+					if cdc, cdr := direction(edges[candidate]); cdc == dr && cdr == -dc {
+						next = candidate
+					}
+				}
+			}
+
+			if next == start {
+				break
+			}
+			current = next
+		}
+
+		ring = removeCollinearPoints(ring)
+		ring = append(ring, ring[0])
+
+		component := edges[start].component
+		if ringArea(ring) > 0 {
+			outers[component] = ring
+		} else {
+			holes[component] = append(holes[component], ring)
+		}
+	}
+
+	polygons := orb.MultiPolygon{}
+	for component, outer := range outers {
+		polygons = append(polygons, append(orb.Polygon{outer}, holes[component]...))
+	}
+
+	return polygons
+}
+
+// labelComponents assigns the same number to all 4-connected visible cells
+func labelComponents(w, h int, isVisible func(c, r int) bool) []int32 {
+	components := make([]int32, w*h)
+	for i := range components {
+		components[i] = -1
+	}
+
+	next := int32(0)
+	stack := []int{}
+
+	for i := range components {
+		if components[i] >= 0 || !isVisible(i%w, i/w) {
+			continue
+		}
+
+		components[i] = next
+		stack = append(stack, i)
+
+		for len(stack) > 0 {
+			cell := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			c, r := cell%w, cell/w
+
+			for _, n := range [4][2]int{{c - 1, r}, {c + 1, r}, {c, r - 1}, {c, r + 1}} {
+				if !isVisible(n[0], n[1]) || components[n[1]*w+n[0]] >= 0 {
+					continue
+				}
+				components[n[1]*w+n[0]] = next
+				stack = append(stack, n[1]*w+n[0])
+			}
+		}
+
+		next++
+	}
+
+	return components
+}
+
+// removeCollinearPoints removes the points of an open ring, which are on a straight line between their neighbors
+func removeCollinearPoints(ring orb.Ring) orb.Ring {
+	result := orb.Ring{}
+
+	for i, p := range ring {
+		prev := ring[(i+len(ring)-1)%len(ring)]
+		next := ring[(i+1)%len(ring)]
+
+		if (p[0]-prev[0])*(next[1]-p[1]) != (p[1]-prev[1])*(next[0]-p[0]) {
+			result = append(result, p)
+		}
+	}
+
+	return result
+}
+
+// ringArea returns the signed area of a closed ring, which is positive for counter-clockwise rings
+func ringArea(ring orb.Ring) float64 {
+	sum := float64(0)
+	for i := 1; i < len(ring); i++ {
+		sum += ring[i-1][0]*ring[i][1] - ring[i][0]*ring[i-1][1]
+	}
+	return sum / 2
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}